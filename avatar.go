@@ -3,13 +3,15 @@ package avatar
 import (
 	"bytes"
 	"errors"
+	"image"
 	"image/color"
 	"image/jpeg"
 	"image/png"
-	"strings"
+	"sync/atomic"
 	"unicode"
+	"unicode/utf8"
 
-	"github.com/dchest/lru"
+	xdraw "golang.org/x/image/draw"
 	"stathat.com/c/consistent"
 )
 
@@ -28,32 +30,59 @@ var (
 
 	defaultColorKey = "45BDF3"
 
-	// ErrUnsupportChar is returned when the character is not supported
-	ErrUnsupportChar = errors.New("unsupported character")
+	// ErrUnsupportChar matches every *UnsupportedCharError with errors.Is.
+	ErrUnsupportChar = errors.New("avatar: unsupported character")
 
-	// ErrUnsupportedEncoding is returned when the given image encoding is not supported
-	ErrUnsupportedEncoding = errors.New("avatar: Unsuppored encoding")
+	// ErrUnsupportedEncoding matches every *UnsupportedFormatError with
+	// errors.Is.
+	ErrUnsupportedEncoding = errors.New("avatar: unsupported encoding")
 	c                      = consistent.New()
 )
 
 // InitialsAvatar represents an initials avatar.
 type InitialsAvatar struct {
-	drawer *drawer
-	cache  *lru.Cache
+	drawer    *drawer
+	cache     Cache
+	flight    flightGroup
+	fallback  image.Image
+	imageID   string // digest of fallback
+	initials  InitialsOptions
+	colorSalt string
 }
 
-// New creates an instance of InitialsAvatar
+// Stats holds usage statistics of an InitialsAvatar.
+type Stats struct {
+	Cache     CacheStats
+	Renders   uint64 // images drawn and encoded by DrawBytes
+	Coalesced uint64 // DrawBytes calls that shared a concurrent identical render
+}
+
+// New creates an instance of InitialsAvatar. The default font is used when
+// fontFile is empty. It panics if the font cannot be loaded, use NewE to
+// handle the error instead.
 func New(fontFile string) *InitialsAvatar {
-	avatar := NewWithConfig(Config{
+	avatar, err := NewE(fontFile)
+	if err != nil {
+		panic(err.Error())
+	}
+	return avatar
+}
+
+// NewE is like New but returns an error instead of panicking.
+func NewE(fontFile string) (*InitialsAvatar, error) {
+	return NewWithConfigE(Config{
 		MaxItems: 1024, // default to 1024 items.
 		FontFile: fontFile,
 	})
-	return avatar
 }
 
 // Config is the configuration object for caching avatar images.
-// This is used in the caching algorithm implemented by  https://github.com/dchest/lru
+// By default this is used in the caching algorithm implemented by  https://github.com/dchest/lru
 type Config struct {
+	// Cache of the encoded images. When nil, an LRU cache limited by MaxItems
+	// and MaxBytes is used.
+	Cache Cache
+
 	// Maximum number of items the cache can contain (unlimited by default).
 	MaxItems int
 
@@ -63,78 +92,287 @@ type Config struct {
 	// TrueType Font file path
 	FontFile string
 
-	// TrueType Font size
+	// TrueType Font file paths tried in order for the characters FontFile
+	// has no glyph for.
+	FallbackFontFiles []string
+
+	// Parsed fonts tried in order after the font files. When no font is
+	// given at all, DefaultFont is used.
+	Fonts []*Font
+
+	// Image drawn instead of the initials when no font has a glyph for them.
+	// It is scaled to the image size. When nil, drawing returns a
+	// *MissingGlyphError instead.
+	FallbackImage image.Image
+
+	// TrueType Font size, 4096 at most. When zero the size is computed for
+	// every image from its size, FontRatio and FontScales.
 	FontSize float64
+
+	// Font size relative to the image size when FontSize is zero (0.6 by
+	// default, 4 at most).
+	FontRatio float64
+
+	// Scales applied to FontRatio by number of initials: the first entry is
+	// used for one initial, the second for two and so on; the last entry is
+	// used for any larger number ({1, 0.8, 0.6} by default, 4 at most).
+	FontScales []float64
+
+	// Default initials options, used for the zero fields of the options of
+	// each draw. A draw overrides a default with an explicit value, such as
+	// CJKFirst, or ignores them all with InitialsOptions.IgnoreDefaults.
+	InitialsOptions InitialsOptions
+
+	// Salt mixed into the names and color keys the background colors are
+	// picked from, so that they get other colors than with other salts.
+	ColorSalt string
 }
 
-// NewWithConfig provides config for LRU Cache.
+// NewWithConfig provides config for LRU Cache. It panics if the font cannot
+// be loaded, use NewWithConfigE to handle the error instead.
 func NewWithConfig(cfg Config) *InitialsAvatar {
+	avatar, err := NewWithConfigE(cfg)
+	if err != nil {
+		panic(err.Error())
+	}
+	return avatar
+}
+
+// NewWithConfigE is like NewWithConfig but returns an error instead of
+// panicking. The error is a *FontError when one of the font files cannot be
+// loaded.
+func NewWithConfigE(cfg Config) (*InitialsAvatar, error) {
 	var err error
 
+	if !validFontSize(cfg.FontSize, maxSize) || !validFontSize(cfg.FontRatio, maxFontRatio) {
+		return nil, ErrInvalidFontSize
+	}
+	for _, scale := range cfg.FontScales {
+		if !validFontSize(scale, maxFontRatio) || scale == 0 {
+			return nil, ErrInvalidFontSize
+		}
+	}
+	if err := cfg.InitialsOptions.validate(); err != nil {
+		return nil, err
+	}
+
 	avatar := new(InitialsAvatar)
-	avatar.drawer, err = newDrawer(cfg.FontFile, cfg.FontSize)
+	var fonts []*Font
+	fontFiles := append([]string{cfg.FontFile}, cfg.FallbackFontFiles...)
+	for _, fontFile := range fontFiles {
+		if fontFile == "" {
+			continue
+		}
+		font, err := parseFont(fontFile)
+		if err != nil {
+			return nil, err
+		}
+		fonts = append(fonts, font)
+	}
+	fonts = append(fonts, cfg.Fonts...)
+	if len(fonts) == 0 {
+		fonts = append(fonts, DefaultFont())
+	}
+
+	avatar.drawer, err = newDrawer(fonts, cfg.FontSize)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
+	}
+	avatar.fallback = cfg.FallbackImage
+	avatar.imageID = imageID(cfg.FallbackImage)
+	avatar.initials = cfg.InitialsOptions
+	avatar.colorSalt = cfg.ColorSalt
+	if cfg.FontRatio > 0 {
+		avatar.drawer.fontRatio = cfg.FontRatio
+	}
+	if len(cfg.FontScales) > 0 {
+		avatar.drawer.fontScales = cfg.FontScales
+	}
+	avatar.cache = cfg.Cache
+	if avatar.cache == nil {
+		avatar.cache = NewLRUCache(cfg.MaxItems, cfg.MaxBytes)
 	}
-	avatar.cache = lru.New(lru.Config{
-		MaxItems: cfg.MaxItems,
-		MaxBytes: cfg.MaxBytes,
-	})
 
-	return avatar
+	return avatar, nil
 }
 
 // DrawToBytes draws an image base on the name and size.
 // Only initials of name will be draw.
 // The size is the side length of the square image. Image is encoded to bytes.
 //
-// You can optionaly specify the encoding of the file. the supported values are png and jpeg for
-// png images and jpeg images respectively. if no encoding is specified then png is used.
+// You can optionaly specify the encoding of the file. the supported values are png, jpeg and svg for
+// png images, jpeg images and svg documents respectively. if no encoding is specified then png is used.
 func (a *InitialsAvatar) DrawToBytes(name string, size int, encoding ...string) ([]byte, error) {
 	if size <= 0 {
-		size = 48 // default size
+		size = defaultSize
 	}
-	name = strings.TrimSpace(name)
-	firstRune := []rune(name)[0]
-	if !isHan(firstRune) && !unicode.IsLetter(firstRune) {
-		return nil, ErrUnsupportChar
+	opts := DrawOptions{Size: size}
+	if len(encoding) > 0 {
+		opts.Format = encoding[0]
 	}
-	initials := getInitials(name)
-	bgcolor := getColorByName(name)
+	return a.DrawBytes(name, opts)
+}
 
+// Draw draws an image of the initials of name styled by opts.
+func (a *InitialsAvatar) Draw(name string, opts DrawOptions) (image.Image, error) {
+	initials, err := a.prepare(name, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return a.draw(initials, &opts)
+}
+
+// DrawBytes draws an image like Draw and encodes it in opts.Format.
+func (a *InitialsAvatar) DrawBytes(name string, opts DrawOptions) ([]byte, error) {
+	initials, err := a.prepare(name, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return a.drawBytes(initials, &opts)
+}
+
+// drawBytes returns the encoded image of the initials, from the cache when
+// it has one.
+func (a *InitialsAvatar) drawBytes(initials string, opts *DrawOptions) ([]byte, error) {
 	// get from cache
-	v, ok := a.cache.GetBytes(lru.Key(initials))
+	key := newCacheKey(initials, a.drawer.fontID, a.imageID, opts).String()
+	v, ok := a.cache.Get(key)
 	if ok {
 		return v, nil
 	}
 
-	m := a.drawer.Draw(initials, size, bgcolor)
+	// concurrent misses of the same key wait for a single render
+	return a.flight.do(key, func() ([]byte, error) {
+		data, err := a.encode(initials, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		// set cache
+		a.cache.Set(key, data)
+
+		return data, nil
+	})
+}
+
+// Color returns the background color picked for a DrawOptions.ColorKey.
+func (a *InitialsAvatar) Color(key string) color.Color {
+	if a.colorSalt != "" {
+		key = a.colorSalt + "\x00" + key
+	}
+	return getColorByName(key)
+}
+
+// Stats returns usage statistics of the cache and of the renders done by
+// DrawBytes.
+func (a *InitialsAvatar) Stats() Stats {
+	return Stats{
+		Cache:     a.cache.Stats(),
+		Renders:   atomic.LoadUint64(&a.flight.renders),
+		Coalesced: atomic.LoadUint64(&a.flight.coalesced),
+	}
+}
+
+// encode draws the initials and encodes the image in o.Format.
+func (a *InitialsAvatar) encode(initials string, o *DrawOptions) ([]byte, error) {
+	if o.Format == FormatSVG {
+		data, err := a.drawer.DrawSVG(initials, o)
+		if _, ok := err.(*MissingGlyphError); !ok || a.fallback == nil {
+			return data, err
+		}
+		m, err := a.draw(initials, o)
+		if err != nil {
+			return nil, err
+		}
+		return imageSVG(m, o)
+	}
+
+	m, err := a.draw(initials, o)
+	if err != nil {
+		return nil, err
+	}
 
 	// encode the image
 	var buf bytes.Buffer
-	enc := "png"
-	if len(encoding) > 0 {
-		enc = encoding[0]
-	}
-	switch enc {
-	case "jpeg":
+	switch o.Format {
+	case FormatJPEG:
 		err := jpeg.Encode(&buf, m, nil)
 		if err != nil {
 			return nil, err
 		}
-	case "png":
+	case FormatPNG:
 		err := png.Encode(&buf, m)
 		if err != nil {
 			return nil, err
 		}
 	default:
-		return nil, ErrUnsupportedEncoding
+		return nil, &UnsupportedFormatError{Format: o.Format}
 	}
+	return buf.Bytes(), nil
+}
 
-	// set cache
-	a.cache.SetBytes(lru.Key(initials), buf.Bytes())
+// draw draws the initials, or the fallback image when a glyph is missing
+// from every font, clipped to o.Shape.
+func (a *InitialsAvatar) draw(initials string, o *DrawOptions) (image.Image, error) {
+	m, err := a.drawer.Draw(initials, o)
+	if _, ok := err.(*MissingGlyphError); ok && a.fallback != nil {
+		dst := image.NewRGBA(image.Rect(0, 0, o.Size, o.Size))
+		xdraw.CatmullRom.Scale(dst, dst.Bounds(), a.fallback, a.fallback.Bounds(), xdraw.Src, nil)
+		m, err = dst, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return clip(m, o), nil
+}
 
-	return buf.Bytes(), nil
+// prepare validates o, fills in its defaults and returns the initials to
+// draw for name.
+func (a *InitialsAvatar) prepare(name string, o *DrawOptions) (string, error) {
+	if err := o.validate(); err != nil {
+		return "", err
+	}
+	o.setDefaults(a.drawer.fontSize, a.initials)
+	if o.Emoji {
+		o.drawable = a.drawer.covers
+	}
+
+	name = cleanName(name)
+	if o.Background == nil {
+		key := o.ColorKey
+		if key == "" {
+			key = name
+		}
+		o.Background = a.Color(key)
+	}
+
+	initials, err := ParseInitials(name, o.InitialsOptions)
+	if err != nil {
+		return "", err
+	}
+	if initials == "" {
+		if name == "" {
+			return "", &EmptyNameError{}
+		}
+		return "", &UnsupportedCharError{Rune: rejectedRune(name)}
+	}
+	if o.FontSize == 0 {
+		o.FontSize = a.drawer.autoFontSize(o.Size-2*o.Padding, len(graphemes(initials)))
+	}
+	return initials, nil
+}
+
+// rejectedRune returns the character of name that gave no initial: its first
+// rune other than spaces and punctuation, which are skipped anyway, or its
+// first rune.
+func rejectedRune(name string) rune {
+	for _, r := range name {
+		if !unicode.IsSpace(r) && !unicode.IsPunct(r) {
+			return r
+		}
+	}
+	r, _ := utf8.DecodeRuneInString(name)
+	return r
 }
 
 // Is it Chinese characters?
@@ -145,6 +383,45 @@ func isHan(r rune) bool {
 	return false
 }
 
+// script is a writing system with its own rules to pick initials.
+type script int
+
+const (
+	scriptOther  script = iota
+	scriptHan           // Chinese characters and Japanese kana
+	scriptHangul        // Korean
+)
+
+// scriptOf returns the script of r.
+func scriptOf(r rune) script {
+	switch {
+	case isHan(r), unicode.In(r, unicode.Hiragana, unicode.Katakana), r == 'ー', r == '々':
+		return scriptHan
+	case unicode.Is(unicode.Hangul, r):
+		return scriptHangul
+	}
+	return scriptOther
+}
+
+// wordScript returns the script all the runes of w are written in, or
+// scriptOther.
+func wordScript(w string) script {
+	s := scriptOther
+	for i, r := range w {
+		if i == 0 {
+			s = scriptOf(r)
+		} else if scriptOf(r) != s {
+			return scriptOther
+		}
+	}
+	return s
+}
+
+// Is it a Chinese, Japanese or Korean character drawn in a full em box?
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
 // random color
 func getColorByName(name string) *color.RGBA {
 	key, err := c.Get(name)
@@ -154,16 +431,12 @@ func getColorByName(name string) *color.RGBA {
 	return avatarBgColors[key]
 }
 
-//TODO: enhance
+// TODO: enhance
 func getInitials(name string) string {
 	if len(name) == 0 {
 		return ""
 	}
-	o := opts{
-		allowEmail: true,
-		limit:      3,
-	}
-	i, _ := parseInitials(strings.NewReader(name), o)
+	i, _ := ParseInitials(name, InitialsOptions{})
 	return i
 }
 
@@ -2,10 +2,19 @@ package avatar
 
 import (
 	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
 	"image/jpeg"
 	"image/png"
 	"io/ioutil"
+	"math"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -13,11 +22,14 @@ const (
 	testFontSize = 75.0
 )
 
+// testFontFile returns the font file the tests draw with, AVATAR_FONT, or ""
+// for the embedded default font when it is not set.
+func testFontFile() string {
+	return os.Getenv("AVATAR_FONT")
+}
+
 func TestInitialsAvatar_DrawToBytes(t *testing.T) {
-	fontFile := os.Getenv("AVATAR_FONT")
-	if fontFile == "" {
-		t.Skip("Font file is needed")
-	}
+	fontFile := testFontFile()
 
 	av := New(fontFile)
 
@@ -36,9 +48,13 @@ func TestInitialsAvatar_DrawToBytes(t *testing.T) {
 	for _, v := range stuffs {
 		raw, err := av.DrawToBytes(v.name, v.size, v.encoding)
 		if err != nil {
-			if err == ErrUnsupportChar {
+			if errors.Is(err, ErrUnsupportChar) {
 				t.Skip("ErrUnsupportChar")
 			}
+			if _, ok := err.(*MissingGlyphError); ok {
+				// the font cannot draw this name
+				continue
+			}
 			t.Error(err)
 		}
 		switch v.encoding {
@@ -75,6 +91,209 @@ func TestGetInitials(t *testing.T) {
 	}
 }
 
+func TestParseInitials(t *testing.T) {
+	names := []struct {
+		full     string
+		opts     InitialsOptions
+		initials string
+		err      error
+	}{
+		{"John Ronald Reuel Tolkien", InitialsOptions{}, "JRR", nil},
+		{"John Ronald Reuel Tolkien", InitialsOptions{Limit: 2}, "JR", nil},
+		{"  john doe ", InitialsOptions{Casing: CasingUpper}, "JD", nil},
+		{"John Doe", InitialsOptions{Casing: CasingLower}, "jd", nil},
+		{"joe@example.com", InitialsOptions{}, "j", nil},
+		{"joe@example.com", InitialsOptions{SkipEmail: true}, "", nil},
+		{"John Doe (dj)", InitialsOptions{Casing: CasingUpper}, "DJ", nil},
+		{"John Doe (abcd)", InitialsOptions{}, "abc", nil},
+		{"John (abcd)", InitialsOptions{Limit: 2}, "ab", nil},
+		{"John (", InitialsOptions{}, "J", nil},
+		{"John Doe (dj", InitialsOptions{}, "JD", nil},
+		{"John Doe ()", InitialsOptions{}, "JD", nil},
+		{strings.Repeat("a", 70000), InitialsOptions{}, "a", nil},
+		{"a " + strings.Repeat("b", 70000), InitialsOptions{}, "ab", nil},
+		{"John", InitialsOptions{Limit: -1}, "", ErrInvalidLimit},
+		{"John", InitialsOptions{Casing: Casing(42)}, "", ErrUnsupportedCasing},
+	}
+
+	for _, v := range names {
+		n, err := ParseInitials(v.full, v.opts)
+		if n != v.initials || err != v.err {
+			t.Errorf("expected %q, %v got %q, %v", v.initials, v.err, n, err)
+		}
+	}
+}
+
+func TestInitialsOptions_setDefaults(t *testing.T) {
+	def := InitialsOptions{
+		Casing:       CasingUpper,
+		CJK:          CJKGivenName,
+		Hangul:       HangulChoseong,
+		SplitHandles: true,
+	}
+	stuffs := []struct {
+		opts, want InitialsOptions
+	}{
+		{InitialsOptions{}, InitialsOptions{Limit: 3, Casing: CasingUpper, CJK: CJKGivenName, Hangul: HangulChoseong, SplitHandles: true}},
+		{InitialsOptions{Casing: CasingNone, CJK: CJKFirst, Hangul: HangulFirst}, InitialsOptions{Limit: 3, Casing: CasingNone, CJK: CJKFirst, Hangul: HangulFirst, SplitHandles: true}},
+		{InitialsOptions{IgnoreDefaults: true}, InitialsOptions{Limit: 3, Casing: CasingNone, CJK: CJKFirst, Hangul: HangulFirst, IgnoreDefaults: true}},
+	}
+
+	for _, v := range stuffs {
+		o := v.opts
+		o.setDefaults(def)
+		o.Words = NameWords{}
+		if !reflect.DeepEqual(o, v.want) {
+			t.Errorf("%+v: expected %+v got %+v", v.opts, v.want, o)
+		}
+	}
+}
+
+func TestParseInitialsSymbols(t *testing.T) {
+	digits := InitialsOptions{Digits: true}
+	emoji := InitialsOptions{Emoji: true}
+	stuffs := []struct {
+		name     string
+		opts     InitialsOptions
+		initials string
+	}{
+		{"42 Analytics", InitialsOptions{}, "A"},
+		{"42 Analytics", digits, "4A"},
+		{"Team 7", digits, "T7"},
+		{"#general", InitialsOptions{}, "g"},
+		{"#general", InitialsOptions{Symbols: "#"}, "#"},
+		{"-- John -- Doe", InitialsOptions{}, "JD"},
+		{"(John) Doe", InitialsOptions{}, "JD"},
+		{"\"John\" Doe", InitialsOptions{}, "JD"},
+		{"...", InitialsOptions{}, ""},
+		{"...", InitialsOptions{Fallback: "?"}, "?"},
+		{"", InitialsOptions{Fallback: "?"}, "?"},
+		{"42", InitialsOptions{Fallback: "#"}, "#"},
+		{"R&D Team", InitialsOptions{}, "RT"},
+		{"R & D", InitialsOptions{Symbols: "&"}, "R&D"},
+		{"John (12345)", InitialsOptions{}, "J"},
+		{"John (12345)", digits, "123"},
+		{"John (#$%)", InitialsOptions{}, "J"},
+		{"John (#$%)", InitialsOptions{Symbols: "#"}, "#"},
+		{"John (d.j)", InitialsOptions{}, "dj"},
+		{"\U0001f680 Launch", InitialsOptions{}, "L"},
+		{"\U0001f680 Launch", emoji, "\U0001f680L"},
+		{"\U0001f680Launch", InitialsOptions{}, "L"},
+		{"\U0001f469\u200d\U0001f4bb Dev", emoji, "\U0001f469\u200d\U0001f4bbD"},
+		{"\U0001f1eb\U0001f1f7 France", emoji, "\U0001f1eb\U0001f1f7F"},
+		{"1\ufe0f\u20e3 One", digits, "O"},
+		{"1\ufe0f\u20e3 One", emoji, "1\ufe0f\u20e3O"},
+		{"\u2764\ufe0f love", InitialsOptions{Emoji: true, Casing: CasingUpper}, "\u2764\ufe0fL"},
+	}
+
+	for _, v := range stuffs {
+		initials, err := ParseInitials(v.name, v.opts)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", v.name, err)
+		}
+		if initials != v.initials {
+			t.Errorf("%s: expected %q got %q", v.name, v.initials, initials)
+		}
+	}
+}
+
+func TestInitialsAvatar_DrawSymbols(t *testing.T) {
+	av := New(testFontFile())
+
+	stuffs := []struct {
+		name string
+		opts InitialsOptions
+		err  error
+	}{
+		{"42 Analytics", InitialsOptions{}, nil},
+		{"#general", InitialsOptions{}, nil},
+		{"42", InitialsOptions{}, &UnsupportedCharError{Rune: '4'}},
+		{"42", InitialsOptions{Digits: true}, nil},
+		{"", InitialsOptions{}, &EmptyNameError{}},
+		{"   ", InitialsOptions{}, &EmptyNameError{}},
+		{"   ", InitialsOptions{Fallback: "?"}, nil},
+		{"#", InitialsOptions{Symbols: "#"}, nil},
+	}
+
+	for _, v := range stuffs {
+		if _, err := av.DrawBytes(v.name, DrawOptions{InitialsOptions: v.opts}); !reflect.DeepEqual(err, v.err) {
+			t.Errorf("%q: expected %v got %v", v.name, v.err, err)
+		}
+	}
+
+	// a fallback set for the instance
+	av = NewWithConfig(Config{InitialsOptions: InitialsOptions{Fallback: "?"}})
+	want, err := av.DrawBytes("?", DrawOptions{Background: color.Black, InitialsOptions: InitialsOptions{Symbols: "?"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := av.DrawBytes("***", DrawOptions{Background: color.Black})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("expected the fallback initials")
+	}
+}
+
+func TestInitialsAvatar_DrawEmoji(t *testing.T) {
+	av := New(testFontFile())
+
+	stuffs := []struct {
+		name, emoji, want string
+	}{
+		{"\U0001f680 Launch", "\U0001f680", "Launch"},
+		{"\U0001f680Launch", "\U0001f680", "Launch"},
+		{"1\ufe0f\u20e3 One", "1\ufe0f\u20e3", "One"},
+		{"\U0001f680", "\U0001f680", "?"},
+	}
+
+	// emoji the fonts can't draw are skipped for the next initial
+	for _, v := range stuffs {
+		if av.drawer.covers(v.emoji) {
+			t.Logf("%q: the font has the emoji", v.emoji)
+			continue
+		}
+		opts := DrawOptions{Background: color.Black, InitialsOptions: InitialsOptions{Emoji: true, Fallback: "?"}}
+		got, err := av.DrawBytes(v.name, opts)
+		if err != nil {
+			t.Fatalf("%q: %v", v.name, err)
+		}
+		want, err := av.DrawBytes(v.want, DrawOptions{Background: color.Black, InitialsOptions: InitialsOptions{Symbols: "?"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%q: expected the initials of %q", v.name, v.want)
+		}
+	}
+}
+
+func TestInitialsAvatar_DrawInitialsOptions(t *testing.T) {
+	fontFile := testFontFile()
+
+	av := New(fontFile)
+	name := "John Ronald Reuel Tolkien"
+	opts := InitialsOptions{Limit: 2, Casing: CasingLower}
+
+	initials, err := ParseInitials(name, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bg := getColorByName(name)
+	want, err := av.DrawBytes("j r", DrawOptions{Background: bg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := av.DrawBytes(name, DrawOptions{Background: bg, InitialsOptions: opts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if initials != "jr" || !bytes.Equal(got, want) {
+		t.Errorf("expected the image of %q", initials)
+	}
+}
+
 func TestParseFont(t *testing.T) {
 	fileNotExists := "xxxxxxx.ttf"
 	_, err := parseFont(fileNotExists)
@@ -82,7 +301,7 @@ func TestParseFont(t *testing.T) {
 		t.Error("should return error")
 	}
 
-	_, err = newDrawer(fileNotExists, testFontSize)
+	_, err = NewE(fileNotExists)
 	if err == nil {
 		t.Error("should return error")
 	}
@@ -94,13 +313,469 @@ func TestParseFont(t *testing.T) {
 	if err == nil {
 		t.Error("should return error")
 	}
-	_, err = newDrawer(fileExistsButNotTTF.Name(), testFontSize)
+	_, err = NewE(fileExistsButNotTTF.Name())
 	if err == nil {
 		t.Error("should return error")
 	}
-	_, err = newDrawer("", testFontSize)
+	_, err = newDrawer(nil, testFontSize)
 	if err == nil {
 		t.Error("should return error")
 	}
 
 }
+
+func TestDrawOptions_validate(t *testing.T) {
+	stuffs := []struct {
+		opts DrawOptions
+		err  error
+	}{
+		{DrawOptions{}, nil},
+		{DrawOptions{Size: 64, Format: FormatJPEG, Padding: 8, InitialsOptions: InitialsOptions{Casing: CasingUpper}}, nil},
+		{DrawOptions{Size: -1}, ErrInvalidSize},
+		{DrawOptions{Size: 1 << 30}, ErrInvalidSize},
+		{DrawOptions{Format: "gif"}, ErrUnsupportedEncoding},
+		{DrawOptions{Shape: Shape(42)}, ErrUnsupportedShape},
+		{DrawOptions{FontSize: -1}, ErrInvalidFontSize},
+		{DrawOptions{FontSize: math.NaN()}, ErrInvalidFontSize},
+		{DrawOptions{FontSize: math.Inf(1)}, ErrInvalidFontSize},
+		{DrawOptions{FontSize: 15000}, ErrInvalidFontSize},
+		{DrawOptions{Size: 64, FontSize: 20000}, ErrInvalidFontSize},
+		{DrawOptions{FontSize: 4096}, nil},
+		{DrawOptions{InitialsOptions: InitialsOptions{Limit: -1}}, ErrInvalidLimit},
+		{DrawOptions{InitialsOptions: InitialsOptions{Casing: Casing(42)}}, ErrUnsupportedCasing},
+		{DrawOptions{Padding: -1}, ErrInvalidPadding},
+		{DrawOptions{Size: 20, Padding: 10}, ErrInvalidPadding},
+		{DrawOptions{Padding: 24}, ErrInvalidPadding},
+		{DrawOptions{Padding: math.MaxInt64 / 2}, ErrInvalidPadding},
+		{DrawOptions{Shape: ShapeRoundedRect, Radius: math.MaxInt64/2 + 1}, ErrInvalidRadius},
+		{DrawOptions{Size: 48, Shape: ShapeRoundedRect, Radius: 24}, nil},
+		{DrawOptions{Size: 49, Shape: ShapeRoundedRect, Radius: 25}, ErrInvalidRadius},
+	}
+
+	for _, v := range stuffs {
+		if err := v.opts.validate(); !errors.Is(err, v.err) {
+			t.Errorf("%+v: expected %v got %v", v.opts, v.err, err)
+		}
+	}
+}
+
+func TestInitialsAvatar_Draw(t *testing.T) {
+	fontFile := testFontFile()
+
+	av := New(fontFile)
+	bg := color.RGBA{10, 20, 30, 255}
+
+	m, err := av.Draw("John Doe", DrawOptions{
+		Size:       64,
+		Background: bg,
+		Foreground: color.Black,
+		FontSize:   testFontSize / 2,
+		Padding:    4,
+
+		InitialsOptions: InitialsOptions{Casing: CasingUpper},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := m.Bounds(); b.Dx() != 64 || b.Dy() != 64 {
+		t.Errorf("expected 64x64 got %v", b)
+	}
+	if got := color.RGBAModel.Convert(m.At(0, 0)); got != bg {
+		t.Errorf("expected background %v got %v", bg, got)
+	}
+
+	if _, err := av.Draw("John Doe", DrawOptions{Format: "gif"}); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("expected %v got %v", ErrUnsupportedEncoding, err)
+	}
+}
+
+func TestNewWithConfigE(t *testing.T) {
+	if _, err := NewWithConfigE(Config{}); err != nil {
+		t.Errorf("the default font should be used, got %v", err)
+	}
+	for _, cfg := range []Config{
+		{FontSize: -1},
+		{FontSize: math.NaN()},
+		{FontRatio: math.Inf(1)},
+		{FontRatio: 300},
+		{FontSize: 15000},
+		{FontScales: []float64{1, 100}},
+		{FontScales: []float64{1, 0}},
+		{FontScales: []float64{math.NaN()}},
+	} {
+		if _, err := NewWithConfigE(cfg); err != ErrInvalidFontSize {
+			t.Errorf("%+v: expected %v got %v", cfg, ErrInvalidFontSize, err)
+		}
+	}
+
+	_, err := NewE("xxxxxxx.ttf")
+	if !errors.Is(err, ErrInvalidFont) {
+		t.Errorf("expected %v got %v", ErrInvalidFont, err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the cause to be %v got %v", os.ErrNotExist, err)
+	}
+	var fe *FontError
+	if !errors.As(err, &fe) || fe.File != "xxxxxxx.ttf" {
+		t.Errorf("expected a *FontError for xxxxxxx.ttf got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("New should panic on an invalid font")
+		}
+	}()
+	New("xxxxxxx.ttf")
+}
+
+// inkBounds returns the bounds of the pixels of m that differ from bg.
+func inkBounds(m image.Image, bg color.Color) image.Rectangle {
+	want := color.RGBAModel.Convert(bg)
+	var r image.Rectangle
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if color.RGBAModel.Convert(m.At(x, y)) != want {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func TestDrawer_autoFontSize(t *testing.T) {
+	g := &drawer{fontRatio: 0.5, fontScales: []float64{1, 0.5}}
+	stuffs := []struct {
+		box, n int
+		size   float64
+	}{
+		{100, 1, 50},
+		{100, 2, 25},
+		{100, 3, 25},
+		{100, 0, 50},
+		{15, 1, 8},
+		{1, 2, 1},
+		{maxSize * 4, 1, maxSize},
+	}
+	for _, v := range stuffs {
+		if size := g.autoFontSize(v.box, v.n); size != v.size {
+			t.Errorf("%d initials in %dpx: expected %v got %v", v.n, v.box, v.size, size)
+		}
+	}
+}
+
+func TestInitialsAvatar_DrawAutoFontSize(t *testing.T) {
+	fontFile := testFontFile()
+
+	av := New(fontFile)
+	bg := color.Black
+
+	// the initials should cover about the same part of every image
+	var ratio float64
+	for _, size := range []int{16, 64, 512} {
+		m, err := av.Draw("Hello", DrawOptions{Size: size, Background: bg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := float64(inkBounds(m, bg).Dy()) / float64(size)
+		if ratio == 0 {
+			ratio = r
+		}
+		if r < 0.2 || math.Abs(r-ratio) > 0.1 {
+			t.Errorf("%dpx: initials height ratio %.2f, expected about %.2f", size, r, ratio)
+		}
+	}
+
+	one, _ := av.Draw("Hello", DrawOptions{Size: 128, Background: bg})
+	three, _ := av.Draw("Hello Big World", DrawOptions{Size: 128, Background: bg})
+	if w := inkBounds(three, bg).Dx(); w >= 128 {
+		t.Errorf("three initials overflow the image: %dpx wide", w)
+	}
+	if inkBounds(three, bg).Dy() >= inkBounds(one, bg).Dy() {
+		t.Error("three initials should be drawn smaller than one")
+	}
+}
+
+func TestInitialsAvatar_DrawManyFontSizes(t *testing.T) {
+	av := New(testFontFile())
+	opts := DrawOptions{Background: color.Black, Format: FormatPNG, FontSize: 20}
+	want, err := av.DrawBytes("Hello", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// faces of many sizes are not all kept, and draws stay right
+	for i := 0; i < 3*maxFacePools; i++ {
+		if _, err := av.Draw("Hello", DrawOptions{FontSize: 10 + float64(i)/8}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(av.drawer.faces); n > maxFacePools {
+		t.Errorf("expected at most %d face pools got %d", maxFacePools, n)
+	}
+	m, err := av.Draw("Hello", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Error("expected the same image after dropping faces")
+	}
+}
+
+func TestGlyphCacheEntries(t *testing.T) {
+	av := New(testFontFile())
+	ttf := av.drawer.fonts[0].ttf
+	if n := glyphCacheEntries(ttf, 24, 72); n != 512 {
+		t.Errorf("expected 512 entries at small sizes got %d", n)
+	}
+
+	// the largest avatars get a small cache instead of gigabytes
+	if n := glyphCacheEntries(ttf, maxSize, 72); n < 1 || n > 4 {
+		t.Errorf("expected at most 4 entries at the largest size got %d", n)
+	}
+}
+
+func TestInitialsAvatar_DrawCentering(t *testing.T) {
+	fontFile := testFontFile()
+
+	av := New(fontFile)
+	bg := color.Black
+	size := 128
+
+	for _, name := range []string{"John Doe", "Adam Bell Cole", "Wendy gray", "Ivy", "jay"} {
+		m, err := av.Draw(name, DrawOptions{Size: size, Background: bg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := inkBounds(m, bg)
+		left, right := r.Min.X, size-r.Max.X
+		top, bottom := r.Min.Y, size-r.Max.Y
+		if left-right > 2 || right-left > 2 || top-bottom > 2 || bottom-top > 2 {
+			t.Errorf("%s: off center, margins left %d right %d top %d bottom %d", name, left, right, top, bottom)
+		}
+	}
+
+	// with baseline centering capitals line up whether or not the initials
+	// have descenders.
+	var top int
+	for i, name := range []string{"Hello World", "Hello gray"} {
+		m, err := av.Draw(name, DrawOptions{Size: size, Background: bg, Centering: CenterBaseline})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := inkBounds(m, bg)
+		if i == 0 {
+			top = r.Min.Y
+		} else if r.Min.Y != top {
+			t.Errorf("%s: expected top at %d got %d", name, top, r.Min.Y)
+		}
+	}
+}
+
+func TestInitialsAvatar_DrawFallbackFonts(t *testing.T) {
+	fontFile := testFontFile()
+
+	// Luxi Sans only covers Latin-1, the test font fills in the rest.
+	luxi := "vendor/github.com/golang/freetype/testdata/luxisr.ttf"
+	cfg := Config{FontFile: luxi, Fonts: []*Font{DefaultFont()}}
+	if fontFile != "" {
+		cfg = Config{FontFile: luxi, FallbackFontFiles: []string{fontFile}}
+	}
+	av := NewWithConfig(cfg)
+
+	var name string
+	for _, n := range []string{"Дмитрий Иванов", "孔子", "Αλέξανδρος", "أحمد"} {
+		if r := []rune(n)[0]; av.drawer.fontFor(r) == 1 {
+			name = n
+			break
+		}
+	}
+	if name == "" {
+		t.Skip("the test font has no glyphs missing from Luxi Sans")
+	}
+
+	bg := color.Black
+	m, err := av.Draw("Hi "+name, DrawOptions{Size: 64, Background: bg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inkBounds(m, bg).Empty() {
+		t.Errorf("%s: nothing drawn", name)
+	}
+	runs, err := av.drawer.runs("H"+string([]rune(name)[0]), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer av.drawer.release(runs)
+	if len(runs) != 2 {
+		t.Errorf("expected 2 runs got %d", len(runs))
+	}
+}
+
+func TestInitialsAvatar_DrawMissingGlyph(t *testing.T) {
+	fontFile := testFontFile()
+
+	// U+17000 is a Tangut letter, no common font has it.
+	name := "\U00017000"
+	_, err := New(fontFile).DrawToBytes(name, 32)
+	if e, ok := err.(*MissingGlyphError); !ok || e.Rune != 0x17000 {
+		t.Errorf("expected a *MissingGlyphError got %v", err)
+	}
+
+	red := color.RGBA{255, 0, 0, 255}
+	fallback := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(fallback, fallback.Bounds(), &image.Uniform{red}, image.ZP, draw.Src)
+	av := NewWithConfig(Config{
+		FontFile:      fontFile,
+		FallbackImage: fallback,
+	})
+	m, err := av.Draw(name, DrawOptions{Size: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := color.RGBAModel.Convert(m.At(16, 16)); got != red {
+		t.Errorf("expected the fallback image got %v", got)
+	}
+}
+
+func TestDefaultFont(t *testing.T) {
+	av, err := NewE("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if av.drawer.fontID != DefaultFont().id {
+		t.Error("expected the default font")
+	}
+	bg := color.Black
+	m, err := av.Draw("Hello", DrawOptions{Background: bg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inkBounds(m, bg).Empty() {
+		t.Error("nothing drawn")
+	}
+}
+
+func TestReadFont(t *testing.T) {
+	f, err := os.Open("resource/fonts/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	font, err := ReadFont(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if font.id != DefaultFont().id {
+		t.Error("expected the same font as the default font")
+	}
+
+	_, err = ParseFont([]byte("not a font"))
+	if !errors.Is(err, ErrInvalidFont) {
+		t.Errorf("expected %v got %v", ErrInvalidFont, err)
+	}
+}
+
+func TestInitialsAvatar_DrawConcurrent(t *testing.T) {
+	// render every time so that draws share faces, not cached bytes
+	av, err := NewWithConfigE(Config{
+		FontFile: testFontFile(),
+		Cache:    NewNopCache(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stuffs := []struct {
+		name     string
+		size     int
+		encoding string
+	}{
+		{"Swordsmen", 22, "png"},
+		{"Condor Heroes", 30, "jpeg"},
+		{"Condor Heroes", 64, "png"},
+		{"Hao Chen", 48, "svg"},
+		{"Wei Lin", 0, "png"},
+		{"Wei Lin", 120, "png"},
+		{"Ørjan Åsmund", 36, "svg"},
+	}
+
+	want := make([][]byte, len(stuffs))
+	for i, v := range stuffs {
+		want[i], err = av.DrawToBytes(v.name, v.size, v.encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const goroutines, rounds = 16, 20
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				i := (g + r) % len(stuffs)
+				v := stuffs[i]
+				raw, err := av.DrawToBytes(v.name, v.size, v.encoding)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !bytes.Equal(raw, want[i]) {
+					t.Errorf("%q at size %d differs from the serial draw", v.name, v.size)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestInitialsAvatar_DrawColorKey(t *testing.T) {
+	fontFile := testFontFile()
+	av := New(fontFile)
+
+	background := func(av *InitialsAvatar, name, key string) color.Color {
+		m, err := av.Draw(name, DrawOptions{ColorKey: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return color.RGBAModel.Convert(m.At(0, 0))
+	}
+	rgba := func(c color.Color) color.Color { return color.RGBAModel.Convert(c) }
+
+	// the name picks the color without a key
+	if got, want := background(av, "Jon", ""), rgba(getColorByName("Jon")); got != want {
+		t.Errorf("expected %v got %v", want, got)
+	}
+	// the key keeps the color across renames
+	jon := background(av, "Jon", "user-1")
+	if got := background(av, "Jonathan", "user-1"); got != jon {
+		t.Errorf("expected %v got %v", jon, got)
+	}
+	if got, want := jon, rgba(av.Color("user-1")); got != want {
+		t.Errorf("expected %v got %v", want, got)
+	}
+
+	// a salt picks other colors for some keys
+	salted := NewWithConfig(Config{FontFile: fontFile, ColorSalt: "pepper"})
+	if got, want := background(salted, "Jon", "user-1"), rgba(salted.Color("user-1")); got != want {
+		t.Errorf("expected %v got %v", want, got)
+	}
+	differ := false
+	for i := 0; i < 20; i++ {
+		key := "user-" + strconv.Itoa(i)
+		if rgba(salted.Color(key)) != rgba(av.Color(key)) {
+			differ = true
+		}
+	}
+	if !differ {
+		t.Error("expected the salt to change colors")
+	}
+}
@@ -0,0 +1,198 @@
+package avatar
+
+import "unicode"
+
+// bidiClass is a simplified Bidi_Class of a rune, see
+// https://www.unicode.org/reports/tr9/.
+type bidiClass int
+
+const (
+	bidiON  bidiClass = iota // neutral
+	bidiL                    // left to right
+	bidiR                    // right to left
+	bidiAL                   // Arabic letter
+	bidiEN                   // European number
+	bidiAN                   // Arabic number
+	bidiNSM                  // combining mark
+)
+
+// bidiClassOf returns the bidi class of r.
+func bidiClassOf(r rune) bidiClass {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return bidiNSM
+	case '0' <= r && r <= '9', 0x06f0 <= r && r <= 0x06f9:
+		return bidiEN
+	case 0x0660 <= r && r <= 0x0669, 0x066b <= r && r <= 0x066c:
+		return bidiAN
+	case unicode.In(r, unicode.Arabic, unicode.Syriac, unicode.Thaana) && (unicode.IsLetter(r) || r == 0x061b || r == 0x061f):
+		return bidiAL
+	case unicode.In(r, unicode.Hebrew, unicode.Nko, unicode.Samaritan, unicode.Mandaic):
+		return bidiR
+	case unicode.IsLetter(r), unicode.Is(unicode.Mc, r):
+		return bidiL
+	}
+	return bidiON
+}
+
+// mirrors are the pairs of characters drawn mirrored in right-to-left text.
+var mirrors = map[rune]rune{
+	'(': ')', ')': '(',
+	'[': ']', ']': '[',
+	'{': '}', '}': '{',
+	'<': '>', '>': '<',
+	'«': '»', '»': '«',
+}
+
+// bidiLevels returns the embedding level of every rune of rs, which has no
+// explicit directional formatting. The paragraph direction is the one of
+// the first strong rune.
+func bidiLevels(rs []rune) []int {
+	classes := make([]bidiClass, len(rs))
+	para := 0
+	found := false
+	for i, r := range rs {
+		classes[i] = bidiClassOf(r)
+		if !found {
+			switch classes[i] {
+			case bidiL:
+				found = true
+			case bidiR, bidiAL:
+				para, found = 1, true
+			}
+		}
+	}
+	sos := bidiL
+	if para == 1 {
+		sos = bidiR
+	}
+
+	// W1-W7: resolve weak types
+	prev, strong := sos, sos
+	for i, c := range classes {
+		if c == bidiNSM {
+			c = prev
+		}
+		switch c {
+		case bidiL, bidiR:
+			strong = c
+		case bidiAL:
+			strong = c
+			c = bidiR
+		case bidiEN:
+			switch strong {
+			case bidiAL:
+				c = bidiAN
+			case bidiL:
+				c = bidiL
+			}
+		}
+		classes[i] = c
+		prev = c
+	}
+
+	// N1-N2: neutrals take the direction of the surrounding strong types,
+	// numbers counting as right to left
+	dir := func(c bidiClass) bidiClass {
+		if c == bidiEN || c == bidiAN {
+			return bidiR
+		}
+		return c
+	}
+	for i := 0; i < len(classes); {
+		if classes[i] != bidiON {
+			i++
+			continue
+		}
+		j := i
+		for j < len(classes) && classes[j] == bidiON {
+			j++
+		}
+		before, after := sos, sos
+		if i > 0 {
+			before = dir(classes[i-1])
+		}
+		if j < len(classes) {
+			after = dir(classes[j])
+		}
+		c := sos
+		if before == after {
+			c = before
+		}
+		for ; i < j; i++ {
+			classes[i] = c
+		}
+	}
+
+	// I1-I2: implicit levels
+	levels := make([]int, len(rs))
+	for i, c := range classes {
+		switch {
+		case para == 0 && c == bidiR:
+			levels[i] = 1
+		case para == 0 && (c == bidiEN || c == bidiAN):
+			levels[i] = 2
+		case para == 1 && (c == bidiL || c == bidiEN || c == bidiAN):
+			levels[i] = 2
+		default:
+			levels[i] = para
+		}
+	}
+	return levels
+}
+
+// visualOrder reorders s from logical to visual order, keeping grapheme
+// clusters together and mirroring brackets in right-to-left runs.
+func visualOrder(s string) string {
+	rs := []rune(s)
+	levels := bidiLevels(rs)
+
+	type cluster struct {
+		s     string
+		level int
+	}
+	var cs []cluster
+	maxLevel, minOdd := 0, 3
+	i := 0
+	for _, g := range graphemes(s) {
+		level := levels[i]
+		if level%2 == 1 {
+			if r := []rune(g); len(r) == 1 && mirrors[r[0]] != 0 {
+				g = string(mirrors[r[0]])
+			}
+			if level < minOdd {
+				minOdd = level
+			}
+		}
+		if level > maxLevel {
+			maxLevel = level
+		}
+		cs = append(cs, cluster{g, level})
+		i += len([]rune(g))
+	}
+
+	// L2: reverse every run at or above each level, from the highest level
+	// down to the lowest odd one
+	for level := maxLevel; level >= minOdd; level-- {
+		for i := 0; i < len(cs); {
+			if cs[i].level < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(cs) && cs[j].level >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				cs[a], cs[b] = cs[b], cs[a]
+			}
+			i = j
+		}
+	}
+
+	var out []byte
+	for _, c := range cs {
+		out = append(out, c.s...)
+	}
+	return string(out)
+}
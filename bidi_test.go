@@ -0,0 +1,104 @@
+package avatar
+
+import (
+	"testing"
+)
+
+func TestVisualOrder(t *testing.T) {
+	stuffs := []struct {
+		logical, visual string
+	}{
+		{"JD", "JD"},
+		{"דכ", "כד"},
+		{"Dד", "Dד"},
+		{"דD", "Dד"},
+		{"ש\u05b8\u05c1ל", "לש\u05b8\u05c1"},
+		{"מע", "עמ"},
+		{"ع1", "1ع"},
+		{"ع١٢", "١٢ع"},
+		{"ד(ש)", "(ש)ד"},
+		{"AדכB", "AכדB"},
+	}
+
+	for _, v := range stuffs {
+		if got := visualOrder(v.logical); got != v.visual {
+			t.Errorf("%+q: expected %+q got %+q", v.logical, v.visual, got)
+		}
+	}
+}
+
+func TestShapeArabic(t *testing.T) {
+	stuffs := []struct {
+		s, shaped string
+	}{
+		{"ب", "ﺏ"},
+		{"مع", "ﻣﻊ"},
+		{"بهاء", "ﺑﻬﺎﺀ"},
+		{"دب", "ﺩﺏ"},
+		{"لا", "ﻻ"},
+		{"سلام", "ﺳﻼﻡ"},
+		{"ب\u0650ب", "ﺑ\u0650ﺐ"},
+		{"پژ", "ﭘﮋ"},
+		{"JD", "JD"},
+	}
+
+	for _, v := range stuffs {
+		if got := shapeArabic(v.s); got != v.shaped {
+			t.Errorf("%+q: expected %+q got %+q", v.s, v.shaped, got)
+		}
+	}
+}
+
+func TestParseInitialsRTL(t *testing.T) {
+	stuffs := []struct {
+		name, initials string
+	}{
+		{"محمد علي", "مع"},
+		{"דנה כהן", "דכ"},
+		{"Dana דנה", "Dד"},
+		{"דנה Dana", "דD"},
+		{"\u200fמשה לוי\u200e", "מל"},
+		{"\u202bעמית \u2067רון\u2069\u202c", "ער"},
+	}
+
+	for _, v := range stuffs {
+		initials, err := ParseInitials(v.name, InitialsOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if initials != v.initials {
+			t.Errorf("%+q: expected %+q got %+q", v.name, v.initials, initials)
+		}
+	}
+}
+
+func TestDrawer_layout(t *testing.T) {
+	av := New("")
+	stuffs := []struct {
+		s, layout string
+	}{
+		{"JD", "JD"},
+		{"דכ", "כד"},
+		{"مع", "ﻉﻡ"},
+		{"Dم", "Dﻡ"},
+	}
+
+	for _, v := range stuffs {
+		if got := av.drawer.layout(v.s); got != v.layout {
+			t.Errorf("%+q: expected %+q got %+q", v.s, v.layout, got)
+		}
+	}
+
+	// the letters are kept when the fonts lack their presentation forms
+	luxi, err := parseFont("vendor/github.com/golang/freetype/testdata/luxisr.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := newDrawer([]*Font{luxi}, testFontSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := g.layout("مع"); got != "عم" {
+		t.Errorf("expected %+q got %+q", "عم", got)
+	}
+}
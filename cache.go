@@ -0,0 +1,180 @@
+package avatar
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"sync/atomic"
+
+	"github.com/dchest/lru"
+)
+
+// Cache stores encoded avatars by key. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the value stored under key.
+	Get(key string) (value []byte, ok bool)
+
+	// Set stores value under key.
+	Set(key string, value []byte)
+
+	// Delete removes the value stored under key, if any.
+	Delete(key string)
+
+	// Stats returns usage statistics.
+	Stats() CacheStats
+}
+
+// CacheStats holds usage statistics of a Cache.
+type CacheStats struct {
+	Hits   uint64 // Get calls that found a value
+	Misses uint64 // Get calls that did not
+	Items  int    // number of stored values
+	Bytes  int64  // total size of the stored values
+}
+
+// counters counts cache hits and misses.
+type counters struct {
+	hits, misses uint64
+}
+
+func (c *counters) count(ok bool) {
+	if ok {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+}
+
+func (c *counters) stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
+
+type lruCache struct {
+	counters
+	c *lru.Cache
+}
+
+// NewLRUCache returns an in-memory cache that drops the least recently used
+// values beyond maxItems values or maxBytes bytes. Zero means unlimited.
+// This is the default cache, implemented by https://github.com/dchest/lru.
+func NewLRUCache(maxItems int, maxBytes int64) Cache {
+	return &lruCache{c: lru.New(lru.Config{
+		MaxItems: maxItems,
+		MaxBytes: maxBytes,
+	})}
+}
+
+func (c *lruCache) Get(key string) ([]byte, bool) {
+	v, ok := c.c.GetBytes(key)
+	c.count(ok)
+	return v, ok
+}
+
+func (c *lruCache) Set(key string, value []byte) { c.c.SetBytes(key, value) }
+
+func (c *lruCache) Delete(key string) { c.c.Remove(key) }
+
+func (c *lruCache) Stats() CacheStats {
+	s := c.stats()
+	s.Items = c.c.Len()
+	s.Bytes = c.c.Size()
+	return s
+}
+
+type nopCache struct {
+	counters
+}
+
+// NewNopCache returns a cache that stores nothing, so every avatar is drawn
+// again.
+func NewNopCache() Cache { return new(nopCache) }
+
+func (c *nopCache) Get(key string) ([]byte, bool) {
+	c.count(false)
+	return nil, false
+}
+
+func (c *nopCache) Set(key string, value []byte) {}
+
+func (c *nopCache) Delete(key string) {}
+
+func (c *nopCache) Stats() CacheStats { return c.stats() }
+
+// renderVersion must be bumped whenever a change to the drawing code alters
+// the image produced for the same inputs, so stale images are never served
+// from a cache.
+const renderVersion = 4
+
+// cacheKey identifies an encoded avatar. It holds every input that affects
+// the output bytes.
+type cacheKey struct {
+	version    int
+	initials   string
+	font       string
+	fallback   string
+	fontSize   float64
+	size       int
+	format     string
+	background color.RGBA64
+	foreground color.RGBA64
+	matte      color.RGBA64
+	shape      Shape
+	radius     int
+	centering  Centering
+	padding    int
+}
+
+// String returns a digest of k, usable as a file name.
+func (k cacheKey) String() string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(fmt.Sprintf("%#v", k))))
+}
+
+// newCacheKey builds the key of the initials drawn with the resolved options
+// o, the font identified by font and the fallback image identified by
+// fallback.
+func newCacheKey(initials, font, fallback string, o *DrawOptions) cacheKey {
+	return cacheKey{
+		version:    renderVersion,
+		initials:   initials,
+		font:       font,
+		fallback:   fallback,
+		fontSize:   o.FontSize,
+		size:       o.Size,
+		format:     o.Format,
+		background: color.RGBA64Model.Convert(o.Background).(color.RGBA64),
+		foreground: color.RGBA64Model.Convert(o.Foreground).(color.RGBA64),
+		matte:      color.RGBA64Model.Convert(o.Matte).(color.RGBA64),
+		shape:      o.Shape,
+		radius:     o.Radius,
+		centering:  o.Centering,
+		padding:    o.Padding,
+	}
+}
+
+// imageID returns a digest of the pixels of m, or "" when m is nil.
+func imageID(m image.Image) string {
+	if m == nil {
+		return ""
+	}
+	digest := sha1.New()
+	b := m.Bounds()
+	fmt.Fprint(digest, b)
+	px := make([]byte, 8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := m.At(x, y).RGBA()
+			binary.BigEndian.PutUint16(px[0:], uint16(r))
+			binary.BigEndian.PutUint16(px[2:], uint16(g))
+			binary.BigEndian.PutUint16(px[4:], uint16(bl))
+			binary.BigEndian.PutUint16(px[6:], uint16(a))
+			digest.Write(px)
+		}
+	}
+	return fmt.Sprintf("%x", digest.Sum(nil))
+}
@@ -0,0 +1,286 @@
+package avatar
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewCacheKey(t *testing.T) {
+	base := DrawOptions{
+		Size:       48,
+		Format:     FormatPNG,
+		Background: color.RGBA{69, 189, 243, 255},
+		Foreground: color.White,
+		Matte:      color.White,
+		FontSize:   testFontSize,
+	}
+	k := newCacheKey("JD", "font", "", &base)
+
+	same := base
+	same.Foreground = color.RGBA{255, 255, 255, 255}
+	if newCacheKey("JD", "font", "", &same) != k {
+		t.Error("equal colors in different models should share a key")
+	}
+
+	variants := []func(o *DrawOptions){
+		func(o *DrawOptions) { o.Size = 256 },
+		func(o *DrawOptions) { o.Format = FormatJPEG },
+		func(o *DrawOptions) { o.Background = color.RGBA{224, 143, 112, 255} },
+		func(o *DrawOptions) { o.Foreground = color.Black },
+		func(o *DrawOptions) { o.FontSize = testFontSize / 2 },
+		func(o *DrawOptions) { o.Padding = 4 },
+		func(o *DrawOptions) { o.Shape = ShapeCircle },
+		func(o *DrawOptions) { o.Shape = ShapeRoundedRect; o.Radius = 6 },
+		func(o *DrawOptions) { o.Radius = 6 },
+		func(o *DrawOptions) { o.Matte = color.Black },
+	}
+	for i, f := range variants {
+		o := base
+		f(&o)
+		if newCacheKey("JD", "font", "", &o) == k {
+			t.Errorf("variant %d collides with the base key", i)
+		}
+	}
+	if newCacheKey("JS", "font", "", &base) == k {
+		t.Error("different initials collide")
+	}
+	if newCacheKey("JD", "other font", "", &base) == k {
+		t.Error("different fonts collide")
+	}
+	if newCacheKey("JD", "font", "image", &base) == k {
+		t.Error("different fallback images collide")
+	}
+}
+
+func TestInitialsAvatar_DrawBytesCache(t *testing.T) {
+	fontFile := testFontFile()
+
+	av := New(fontFile)
+
+	stuffs := []struct {
+		size     int
+		encoding string
+	}{
+		{48, "png"},
+		{256, "jpeg"},
+		{48, "jpeg"},
+		{256, "png"},
+		{48, "png"},
+	}
+	for _, v := range stuffs {
+		raw, err := av.DrawToBytes("John Doe", v.size, v.encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format != v.encoding || cfg.Width != v.size {
+			t.Errorf("expected %dpx %s got %dpx %s", v.size, v.encoding, cfg.Width, format)
+		}
+	}
+	if n := av.cache.Stats().Items; n != 4 {
+		t.Errorf("expected 4 cached images got %d", n)
+	}
+}
+
+func TestInitialsAvatar_DrawBytesCacheColors(t *testing.T) {
+	fontFile := testFontFile()
+
+	av := New(fontFile)
+
+	// two names with the same initials but different hashed colors
+	names := []string{"John Doe", "Jane Dunn", "Jim Dale", "Joe Dart", "Jill Dean", "Jack Dry"}
+	var a, b string
+	for _, n := range names[1:] {
+		if *getColorByName(n) != *getColorByName(names[0]) {
+			a, b = names[0], n
+			break
+		}
+	}
+	if a == "" {
+		t.Fatal("no names with different colors")
+	}
+
+	for _, n := range []string{a, b} {
+		raw, err := av.DrawToBytes(n, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _, err := image.Decode(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := color.RGBAModel.Convert(getColorByName(n))
+		if got := color.RGBAModel.Convert(m.At(0, 0)); got != want {
+			t.Errorf("%s: expected background %v got %v", n, want, got)
+		}
+	}
+}
+
+func TestCaches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "avatar-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fc, err := NewFileCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caches := map[string]Cache{
+		"lru":  NewLRUCache(0, 0),
+		"ttl":  NewTTLCache(time.Hour),
+		"file": fc,
+	}
+	for name, c := range caches {
+		if _, ok := c.Get("a"); ok {
+			t.Errorf("%s: unexpected value", name)
+		}
+		c.Set("a", []byte("hello"))
+		c.Set("b", []byte("world!"))
+		c.Set("a", []byte("hi"))
+		if v, ok := c.Get("a"); !ok || string(v) != "hi" {
+			t.Errorf("%s: expected hi got %q", name, v)
+		}
+		c.Delete("b")
+		if _, ok := c.Get("b"); ok {
+			t.Errorf("%s: deleted value found", name)
+		}
+		want := CacheStats{Hits: 1, Misses: 2, Items: 1, Bytes: 2}
+		if s := c.Stats(); s != want {
+			t.Errorf("%s: expected %+v got %+v", name, want, s)
+		}
+	}
+}
+
+func TestNopCache(t *testing.T) {
+	c := NewNopCache()
+	c.Set("a", []byte("hello"))
+	if _, ok := c.Get("a"); ok {
+		t.Error("the nop cache stored a value")
+	}
+	if s := c.Stats(); s != (CacheStats{Misses: 1}) {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestTTLCache(t *testing.T) {
+	c := NewTTLCache(time.Minute).(*ttlCache)
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", []byte("hello"))
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Error("value expired early")
+	}
+	c.Set("b", []byte("world"))
+	now = now.Add(time.Second)
+	if v, ok := c.Get("a"); ok || v != nil {
+		t.Errorf("value did not expire, got %q", v)
+	}
+
+	// expired values are dropped on Set even if nobody asks for them
+	now = now.Add(time.Minute)
+	c.Set("c", []byte("!"))
+	if s := c.Stats(); s.Items != 1 || s.Bytes != 1 {
+		t.Errorf("expected only c left got %+v", s)
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "avatar-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// images survive a restart
+	c, _ := NewFileCache(dir)
+	av := NewWithConfig(Config{Cache: c})
+	raw, err := av.DrawToBytes("John Doe", 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ = NewFileCache(dir)
+	av = NewWithConfig(Config{Cache: c})
+	cached, err := av.DrawToBytes("John Doe", 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, cached) {
+		t.Error("expected the cached image")
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Items != 1 {
+		t.Errorf("expected a hit on one item got %+v", s)
+	}
+
+	// keys are not file paths
+	c.Set("a/b", []byte("ab"))
+	c.Set("c/b", []byte("cb"))
+	if v, ok := c.Get("a/b"); !ok || string(v) != "ab" {
+		t.Errorf("expected ab got %q", v)
+	}
+	c.Delete("..")
+	c.Delete(".")
+	if _, err := os.Stat(dir); err != nil {
+		t.Error(err)
+	}
+	if s := c.Stats(); s.Items != 3 {
+		t.Errorf("expected 3 items got %+v", s)
+	}
+}
+
+func TestInitialsAvatar_DrawBytesNopCache(t *testing.T) {
+	c := NewNopCache()
+	av := NewWithConfig(Config{Cache: c})
+	for i := 0; i < 3; i++ {
+		if _, err := av.DrawToBytes("John Doe", 16); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := c.Stats(); s.Misses != 3 {
+		t.Errorf("expected 3 misses got %+v", s)
+	}
+}
+
+func TestInitialsAvatar_DrawBytesCacheFallback(t *testing.T) {
+	fill := func(c color.Color) image.Image {
+		m := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		draw.Draw(m, m.Bounds(), &image.Uniform{c}, image.ZP, draw.Src)
+		return m
+	}
+
+	// instances with other fallback images share a cache
+	c := NewLRUCache(0, 0)
+	red := NewWithConfig(Config{Cache: c, FallbackImage: fill(color.RGBA{255, 0, 0, 255})})
+	blue := NewWithConfig(Config{Cache: c, FallbackImage: fill(color.RGBA{0, 0, 255, 255})})
+
+	name := "\U00017000"
+	r, err := red.DrawToBytes(name, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := blue.DrawToBytes(name, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(r, b) {
+		t.Error("expected the fallback image of each instance")
+	}
+	if imageID(fill(color.Black)) != imageID(fill(color.RGBA{0, 0, 0, 255})) {
+		t.Error("equal images should share a digest")
+	}
+}
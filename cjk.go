@@ -0,0 +1,147 @@
+package avatar
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// CJKMode selects the initials of Chinese and Japanese names, which are
+// usually written without spaces between the surname and the given name.
+type CJKMode int
+
+const (
+	// CJKDefault uses the mode of Config.InitialsOptions, or CJKFirst.
+	CJKDefault CJKMode = iota
+
+	// CJKFirst takes the first character of the name, the surname, like any
+	// other word.
+	CJKFirst
+
+	// CJKGivenName takes the given name, which tells apart the members of
+	// a family: 欧阳修 gives 修 and 毛泽东 gives 泽东.
+	CJKGivenName
+
+	// CJKSurname takes the whole surname, including compound surnames:
+	// 欧阳修 gives 欧阳.
+	CJKSurname
+
+	// CJKSurnameGivenName takes the first character of the surname and of
+	// the given name: 欧阳修 gives 欧修 and 山田太郎 gives 山太.
+	CJKSurnameGivenName
+)
+
+// compoundSurnames are the Chinese surnames of two characters, in simplified
+// and traditional forms.
+var compoundSurnames = map[string]bool{
+	"欧阳": true, "歐陽": true, "司马": true, "司馬": true, "上官": true,
+	"诸葛": true, "諸葛": true, "东方": true, "東方": true, "皇甫": true,
+	"尉迟": true, "尉遲": true, "公孙": true, "公孫": true, "慕容": true,
+	"长孙": true, "長孫": true, "宇文": true, "司徒": true, "司空": true,
+	"夏侯": true, "轩辕": true, "軒轅": true, "令狐": true, "钟离": true,
+	"鍾離": true, "闾丘": true, "閭丘": true, "独孤": true, "獨孤": true,
+	"南宫": true, "南宮": true, "西门": true, "西門": true, "百里": true,
+	"呼延": true, "端木": true, "申屠": true, "太史": true, "公冶": true,
+	"赫连": true, "赫連": true, "澹台": true, "澹臺": true, "公羊": true,
+	"万俟": true, "萬俟": true, "濮阳": true, "濮陽": true, "淳于": true,
+	"单于": true, "單于": true, "拓跋": true, "第五": true, "左丘": true,
+}
+
+// japaneseSurnames are common Japanese surnames written in kanji.
+var japaneseSurnames = map[string]bool{
+	"佐藤": true, "鈴木": true, "高橋": true, "田中": true, "伊藤": true,
+	"渡辺": true, "渡邊": true, "山本": true, "中村": true, "小林": true,
+	"加藤": true, "吉田": true, "山田": true, "佐々木": true, "山口": true,
+	"松本": true, "井上": true, "木村": true, "斎藤": true, "斉藤": true,
+	"清水": true, "山崎": true, "池田": true, "橋本": true, "阿部": true,
+	"石川": true, "山下": true, "中島": true, "石井": true, "小川": true,
+	"前田": true, "岡田": true, "長谷川": true, "藤田": true, "後藤": true,
+	"近藤": true, "村上": true, "遠藤": true, "青木": true, "坂本": true,
+	"福田": true, "太田": true, "西村": true, "藤井": true, "岡本": true,
+	"藤原": true, "中野": true, "三浦": true, "原田": true, "中川": true,
+	"松田": true, "竹内": true, "小野": true, "田村": true, "中山": true,
+	"和田": true, "石田": true, "森田": true, "上田": true, "柴田": true,
+	"酒井": true, "工藤": true, "横山": true, "宮崎": true, "宮本": true,
+	"内田": true, "高木": true, "安藤": true, "島田": true, "谷口": true,
+	"大野": true, "高田": true, "丸山": true, "今井": true, "河野": true,
+	"藤本": true, "村田": true, "武田": true, "上野": true, "杉山": true,
+	"増田": true, "小山": true, "大塚": true, "平野": true, "菅原": true,
+	"久保": true, "松井": true, "千葉": true, "岩崎": true, "桜井": true,
+	"木下": true, "野口": true, "松尾": true, "菊地": true, "野村": true,
+	"新井": true, "渡部": true, "大西": true, "北村": true, "田辺": true,
+}
+
+// splitCJKName splits a Chinese or Japanese name written without a space
+// into its surname and given name. The given name is empty when no split is
+// found.
+func splitCJKName(name string) (surname, given string) {
+	// a Japanese given name in kana after a surname in kanji
+	for i, r := range name {
+		if i > 0 && unicode.In(r, unicode.Hiragana, unicode.Katakana) {
+			first, _ := utf8.DecodeRuneInString(name)
+			if unicode.Is(unicode.Han, first) {
+				return name[:i], name[i:]
+			}
+			break
+		}
+	}
+
+	// the longest known surname, or the surname alone
+	for _, n := range []int{3, 2} {
+		prefix := runePrefix(name, n)
+		if utf8.RuneCountInString(prefix) < n {
+			continue
+		}
+		if compoundSurnames[prefix] || japaneseSurnames[prefix] {
+			return prefix, name[len(prefix):]
+		}
+	}
+
+	// a name in kana alone cannot be split
+	first, size := utf8.DecodeRuneInString(name)
+	if !unicode.Is(unicode.Han, first) {
+		return name, ""
+	}
+
+	// a Chinese surname of one character
+	return name[:size], name[size:]
+}
+
+// runePrefix returns the first n runes of s.
+func runePrefix(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// cjkInitials returns the initials of a Chinese or Japanese name split in
+// words according to o.CJK. It reports false when the name is not one.
+func cjkInitials(words []string, o InitialsOptions) (string, bool) {
+	if o.CJK == CJKFirst || len(words) == 0 || wordScript(words[0]) != scriptHan {
+		return "", false
+	}
+
+	var surname, given string
+	if len(words) > 1 && wordScript(words[1]) == scriptHan {
+		surname, given = words[0], words[1]
+	} else {
+		surname, given = splitCJKName(words[0])
+	}
+
+	var initials string
+	switch o.CJK {
+	case CJKGivenName:
+		initials = given
+		if given == "" {
+			initials = runePrefix(surname, 1)
+		}
+	case CJKSurname:
+		initials = surname
+	case CJKSurnameGivenName:
+		initials = runePrefix(surname, 1) + runePrefix(given, 1)
+	}
+	return runePrefix(initials, o.Limit), true
+}
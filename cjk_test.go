@@ -0,0 +1,129 @@
+package avatar
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitCJKName(t *testing.T) {
+	stuffs := []struct {
+		name, surname, given string
+	}{
+		{"孔子", "孔", "子"},
+		{"毛泽东", "毛", "泽东"},
+		{"欧阳修", "欧阳", "修"},
+		{"歐陽修", "歐陽", "修"},
+		{"司马相如", "司马", "相如"},
+		{"诸葛亮", "诸葛", "亮"},
+		{"欧阳", "欧阳", ""},
+		{"李", "李", ""},
+		{"山田太郎", "山田", "太郎"},
+		{"佐々木希", "佐々木", "希"},
+		{"長谷川京子", "長谷川", "京子"},
+		{"田中ひろし", "田中", "ひろし"},
+		{"林さくら", "林", "さくら"},
+		{"やまだたろう", "やまだたろう", ""},
+	}
+
+	for _, v := range stuffs {
+		surname, given := splitCJKName(v.name)
+		if surname != v.surname || given != v.given {
+			t.Errorf("%s: expected %q %q got %q %q", v.name, v.surname, v.given, surname, given)
+		}
+	}
+}
+
+func TestParseInitialsCJK(t *testing.T) {
+	stuffs := []struct {
+		name     string
+		mode     CJKMode
+		limit    int
+		initials string
+	}{
+		{"孔子", CJKFirst, 0, "孔"},
+		{"孔子", CJKGivenName, 0, "子"},
+		{"孔子", CJKSurname, 0, "孔"},
+		{"孔子", CJKSurnameGivenName, 0, "孔子"},
+		{"毛泽东", CJKGivenName, 0, "泽东"},
+		{"毛泽东", CJKGivenName, 1, "泽"},
+		{"毛泽东", CJKSurnameGivenName, 0, "毛泽"},
+		{"欧阳修", CJKFirst, 0, "欧"},
+		{"欧阳修", CJKGivenName, 0, "修"},
+		{"欧阳修", CJKSurname, 0, "欧阳"},
+		{"欧阳修", CJKSurnameGivenName, 0, "欧修"},
+		{"欧阳修", CJKSurname, 1, "欧"},
+		{"李", CJKGivenName, 0, "李"},
+		{"李", CJKSurnameGivenName, 0, "李"},
+		{"山田太郎", CJKGivenName, 0, "太郎"},
+		{"山田太郎", CJKSurname, 0, "山田"},
+		{"山田太郎", CJKSurnameGivenName, 0, "山太"},
+		{"山田 太郎", CJKSurnameGivenName, 0, "山太"},
+		{"孔 子", CJKGivenName, 0, "子"},
+		{"田中ひろし", CJKGivenName, 0, "ひろし"},
+		{"田中ひろし", CJKSurnameGivenName, 0, "田ひ"},
+		{"やまだ たろう", CJKGivenName, 0, "たろう"},
+		{"やまだたろう", CJKGivenName, 0, "や"},
+		{"カタカナ", CJKSurname, 0, "カタカ"},
+		// other names are not affected
+		{"John Doe", CJKGivenName, 0, "JD"},
+		{"John 孔子", CJKGivenName, 0, "J孔"},
+	}
+
+	for _, v := range stuffs {
+		initials, err := ParseInitials(v.name, InitialsOptions{CJK: v.mode, Limit: v.limit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if initials != v.initials {
+			t.Errorf("%s: expected %q got %q", v.name, v.initials, initials)
+		}
+	}
+
+	if _, err := ParseInitials("孔子", InitialsOptions{CJK: CJKMode(42)}); err != ErrUnsupportedCJKMode {
+		t.Errorf("expected %v got %v", ErrUnsupportedCJKMode, err)
+	}
+}
+
+func TestInitialsAvatar_DrawCJKMode(t *testing.T) {
+	// set per instance, and overridden per draw
+	av, err := NewWithConfigE(Config{
+		FontFile:        testFontFile(),
+		InitialsOptions: InitialsOptions{CJK: CJKGivenName},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stuffs := []struct {
+		name     string
+		mode     CJKMode
+		initials string
+	}{
+		{"欧阳修", CJKDefault, "修"},
+		{"欧阳修", CJKSurname, "欧阳"},
+		{"欧阳修", CJKFirst, "欧"},
+	}
+
+	plain := New(testFontFile())
+	for _, v := range stuffs {
+		bg := getColorByName(v.name)
+		want, err := plain.DrawBytes(v.initials, DrawOptions{Background: bg})
+		if err != nil {
+			if _, ok := err.(*MissingGlyphError); ok {
+				t.Skip("the font cannot draw Chinese")
+			}
+			t.Fatal(err)
+		}
+		got, err := av.DrawBytes(v.name, DrawOptions{Background: bg, InitialsOptions: InitialsOptions{CJK: v.mode}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: expected the image of %q", v.name, v.initials)
+		}
+	}
+
+	if _, err := NewWithConfigE(Config{InitialsOptions: InitialsOptions{CJK: CJKMode(42)}}); err != ErrUnsupportedCJKMode {
+		t.Errorf("expected %v got %v", ErrUnsupportedCJKMode, err)
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"path/filepath"
 	"strconv"
@@ -17,10 +16,14 @@ type avatarHandler struct {
 	avatar *avatar.InitialsAvatar
 }
 
-func newAvatarHandler(fontFile string) *avatarHandler {
+func newAvatarHandler(fontFile string) (*avatarHandler, error) {
+	a, err := avatar.NewE(fontFile)
+	if err != nil {
+		return nil, err
+	}
 	h := new(avatarHandler)
-	h.avatar = avatar.New(fontFile)
-	return h
+	h.avatar = a
+	return h, nil
 }
 
 func (h *avatarHandler) Get(ctx *echo.Context) error {
@@ -29,7 +32,6 @@ func (h *avatarHandler) Get(ctx *echo.Context) error {
 	if size == "" {
 		size = "120"
 	}
-	//FIXME: auto size
 	sz, err := strconv.Atoi(size)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -48,23 +50,30 @@ func (h *avatarHandler) Get(ctx *echo.Context) error {
 	return nil
 }
 
-func server(ctx *cli.Context) {
+func server(ctx *cli.Context) error {
 	fontFile := ctx.String("fontFile")
 	port := ctx.Int("port")
 
+	if fontFile != "" {
+		fFile, err := filepath.Abs(fontFile)
+		if err != nil {
+			return cli.NewExitError("invalid font file path", 1)
+		}
+		fontFile = fFile
+	}
+	h, err := newAvatarHandler(fontFile)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
 	e := echo.New()
 	e.Use(mw.Logger())
 	e.Use(mw.Recover())
-
-	fFile, err := filepath.Abs(fontFile)
-	if err != nil {
-		log.Fatal("invalid font file path")
-	}
-	h := newAvatarHandler(fFile)
 	e.Get("/:name", h.Get)
 
 	fmt.Printf("starting at :%d ...\n", port)
 	e.Run(fmt.Sprintf(":%d", port))
+	return nil
 }
 
 func serverCommand() cli.Command {
@@ -76,8 +85,7 @@ func serverCommand() cli.Command {
 		Flags: []cli.Flag{
 			cli.StringFlag{
 				Name:  "fontFile",
-				Usage: "tty font file path",
-				Value: "./resource/fonts/Hiragino_Sans_GB_W3.ttf",
+				Usage: "ttf font file path, the embedded DejaVu Sans when empty",
 			},
 			cli.IntFlag{
 				Name:  "port",
@@ -93,7 +101,7 @@ func main() {
 	a.Version = "0.0.1"
 	a.Usage = "Generate an avatar image from a user's initials"
 	a.Authors = []cli.Author{
-		{"holys", "chendahui007@gmail.com"},
+		{Name: "holys", Email: "chendahui007@gmail.com"},
 	}
 	a.Commands = []cli.Command{
 		serverCommand(),
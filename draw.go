@@ -1,11 +1,14 @@
 package avatar
 
 import (
-	"errors"
+	"crypto/sha1"
+	"fmt"
 	"image"
-	"image/color"
 	"image/draw"
-	"io/ioutil"
+	"io"
+	"math"
+	"strings"
+	"sync"
 
 	"github.com/golang/freetype/truetype"
 	"golang.org/x/image/font"
@@ -13,95 +16,338 @@ import (
 )
 
 var (
-	errFontRequired = errors.New("font file is required")
-	errInvalidFont  = errors.New("invalid font")
+	defaultFontRatio  = 0.6
+	defaultFontScales = []float64{1, 0.8, 0.6}
 )
 
+// maxFacePools is the number of font and size pairs whose faces are kept.
+// Font sizes come from callers, so older pools are dropped beyond it.
+const maxFacePools = 64
+
 // drawer draws an image.Image
 type drawer struct {
 	fontSize    float64
+	fontRatio   float64   // font size relative to the image when fontSize is 0
+	fontScales  []float64 // font ratio scale by number of initials
 	dpi         float64
 	fontHinting font.Hinting
-	face        font.Face
-	font        *truetype.Font
+	fonts       []*Font // in fallback order
+	fontID      string  // digest of the font data
+
+	// A face keeps glyph caches and is not safe for concurrent use, so each
+	// draw takes its faces from these pools and puts them back when done.
+	mu    sync.Mutex
+	faces map[faceKey]*sync.Pool
+}
+
+type faceKey struct {
+	font int // index in drawer.fonts
+	size float64
 }
 
-func newDrawer(fontFile string, fontSize float64) (*drawer, error) {
-	if fontFile == "" {
-		return nil, errFontRequired
+// newDrawer returns a drawer for fonts. Glyphs are taken from the first font
+// that has them.
+func newDrawer(fonts []*Font, fontSize float64) (*drawer, error) {
+	if len(fonts) == 0 {
+		return nil, ErrFontRequired
 	}
 	g := new(drawer)
 	g.fontSize = fontSize
+	g.fontRatio = defaultFontRatio
+	g.fontScales = defaultFontScales
 	g.dpi = 72.0
 	g.fontHinting = font.HintingNone
 
-	font, err := parseFont(fontFile)
-	if err != nil {
-		return nil, errInvalidFont
+	g.fonts = fonts
+	g.fontID = fonts[0].id
+	if len(fonts) > 1 {
+		digest := sha1.New()
+		for _, f := range fonts {
+			io.WriteString(digest, f.id)
+		}
+		g.fontID = fmt.Sprintf("%x", digest.Sum(nil))
 	}
-	g.face = truetype.NewFace(font, &truetype.Options{
-		Size:    g.fontSize,
-		DPI:     g.dpi,
-		Hinting: g.fontHinting,
-	})
-
-	g.font = font
+	g.faces = make(map[faceKey]*sync.Pool)
 	return g, nil
 }
 
 // our avatar image is square
-func (g *drawer) Draw(s string, size int, bg *color.RGBA) image.Image {
+func (g *drawer) Draw(s string, o *DrawOptions) (image.Image, error) {
+	size := o.Size
+
+	runs, err := g.runs(s, o.FontSize)
+	if err != nil {
+		return nil, err
+	}
+	defer g.release(runs)
+
 	// draw the background
 	dst := image.NewRGBA(image.Rect(0, 0, size, size))
-	draw.Draw(dst, dst.Bounds(), &image.Uniform{bg}, image.ZP, draw.Src)
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{o.Background}, image.ZP, draw.Src)
 
 	// draw the text
 	drawer := &font.Drawer{
-		Dst:  dst,
-		Src:  image.White,
-		Face: g.face,
+		Dst: dst,
+		Src: &image.Uniform{o.Foreground},
+	}
+	drawer.Dot, err = g.origin(runs, size, o.Centering)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range runs {
+		drawer.Face = r.face
+		drawer.DrawString(r.s)
 	}
 
-	// font index
-	fi := g.font.Index([]rune(s)[0])
+	return dst, nil
+}
 
-	// glyph example: http://www.freetype.org/freetype2/docs/tutorial/metrics.png
-	var gbuf truetype.GlyphBuf
-	var err error
-	fsize := fixed.Int26_6(g.fontSize * g.dpi * (64.0 / 72.0))
-	err = gbuf.Load(g.font, fsize, fi, font.HintingFull)
-	if err != nil {
-		// fixme
-		drawer.DrawString("")
-		return dst
+// run is a part of a string drawn with a single face.
+type run struct {
+	s    string
+	key  faceKey
+	face font.Face
+}
+
+// runs lays out s and splits it into runs of runes that are drawn with the
+// same font, the first one that has a glyph for them. The faces of the runs
+// must be given back with release.
+func (g *drawer) runs(s string, fontSize float64) ([]run, error) {
+	s = g.layout(s)
+	var runs []run
+	start, last := 0, -1
+	for i, r := range s {
+		f := g.fontFor(r)
+		if f < 0 {
+			g.release(runs)
+			return nil, &MissingGlyphError{Rune: r}
+		}
+		if f != last && i > 0 {
+			k := faceKey{last, fontSize}
+			runs = append(runs, run{s[start:i], k, g.getFace(k)})
+			start = i
+		}
+		last = f
+	}
+	if last >= 0 {
+		k := faceKey{last, fontSize}
+		runs = append(runs, run{s[start:], k, g.getFace(k)})
 	}
+	return runs, nil
+}
 
-	// center
-	dY := int((size - int(gbuf.Bounds.Max.Y-gbuf.Bounds.Min.Y)>>6) / 2)
-	dX := int((size - int(gbuf.Bounds.Max.X-gbuf.Bounds.Min.X)>>6) / 2)
-	y := int(gbuf.Bounds.Max.Y>>6) + dY
-	x := 0 - int(gbuf.Bounds.Min.X>>6) + dX
+// layout shapes the Arabic letters of s, when the fonts have glyphs for
+// their presentation forms, and puts s in visual order for drawing from
+// left to right. Each initial is shaped on its own, as the initials of
+// different words must not join into one.
+func (g *drawer) layout(s string) string {
+	var b strings.Builder
+	for _, c := range graphemes(s) {
+		b.WriteString(shapeArabic(c))
+	}
+	if shaped := b.String(); shaped != s && g.covers(shaped) {
+		s = shaped
+	}
+	return visualOrder(s)
+}
 
-	drawer.Dot = fixed.Point26_6{
-		X: fixed.I(x),
-		Y: fixed.I(y),
+// covers reports whether the fonts have a glyph for every rune of s.
+func (g *drawer) covers(s string) bool {
+	for _, r := range s {
+		if g.fontFor(r) < 0 {
+			return false
+		}
 	}
-	drawer.DrawString(s)
+	return true
+}
 
-	return dst
+// release puts the faces of runs back into their pools.
+func (g *drawer) release(runs []run) {
+	for _, r := range runs {
+		g.pool(r.key).Put(r.face)
+	}
 }
 
-// parseFont parse the font file as *truetype.Font (TTF)
-func parseFont(fontFile string) (*truetype.Font, error) {
-	fontBytes, err := ioutil.ReadFile(fontFile)
-	if err != nil {
-		return nil, err
+// fontFor returns the index of the first font that has a glyph for r, or -1.
+func (g *drawer) fontFor(r rune) int {
+	for i, f := range g.fonts {
+		if f.ttf.Index(r) != 0 {
+			return i
+		}
 	}
+	return -1
+}
 
-	font, err := truetype.Parse(fontBytes)
+// origin returns the dot at which runs are drawn to be centered in a square
+// of the given side length.
+//
+// glyph example: http://www.freetype.org/freetype2/docs/tutorial/metrics.png
+func (g *drawer) origin(runs []run, size int, c Centering) (fixed.Point26_6, error) {
+	center := fixed.I(size) / 2
+	bounds, advance, err := boundRuns(runs)
 	if err != nil {
-		return nil, err
+		return fixed.Point26_6{}, err
 	}
 
-	return font, nil
+	switch c {
+	case CenterBaseline:
+		// center the advance horizontally and the band between the baseline
+		// and the cap height vertically, so every string shares a baseline.
+		return fixed.Point26_6{
+			X: center - advance/2,
+			Y: center + opticalCenter(runs),
+		}, nil
+	default:
+		// center the ink bounding box.
+		return fixed.Point26_6{
+			X: center - (bounds.Min.X+bounds.Max.X)/2,
+			Y: center - (bounds.Min.Y+bounds.Max.Y)/2,
+		}, nil
+	}
+}
+
+// opticalCenter returns the height above the baseline that s is centered on:
+// half the cap height for alphabetic text, or the middle of the em box for
+// CJK text whose glyphs fill it.
+func opticalCenter(runs []run) fixed.Int26_6 {
+	for _, run := range runs {
+		for _, r := range run.s {
+			if isCJK(r) {
+				m := run.face.Metrics()
+				return (m.Ascent - m.Descent) / 2
+			}
+		}
+	}
+	if len(runs) == 0 {
+		return 0
+	}
+	face := runs[0].face
+	if b, _, ok := face.GlyphBounds('H'); ok && b.Min.Y < 0 {
+		return -b.Min.Y / 2
+	}
+	return face.Metrics().Ascent * 7 / 20
+}
+
+// boundRuns returns the ink bounds of runs drawn one after the other at a dot
+// equal to the origin, and how far the dot advances. Kerning is applied
+// between glyphs of the same run. The error is a *FontError when a glyph
+// cannot be loaded.
+func boundRuns(runs []run) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, err error) {
+	for _, run := range runs {
+		prevC := rune(-1)
+		for _, c := range run.s {
+			if prevC >= 0 {
+				advance += run.face.Kern(prevC, c)
+			}
+			b, a, ok := run.face.GlyphBounds(c)
+			if !ok {
+				return bounds, advance, &FontError{Err: fmt.Errorf("cannot load the glyph of %q", c)}
+			}
+			b.Min.X += advance
+			b.Max.X += advance
+			if b.Min.X < b.Max.X && b.Min.Y < b.Max.Y {
+				bounds = unionRect(bounds, b)
+			}
+			advance += a
+			prevC = c
+		}
+	}
+	return bounds, advance, nil
+}
+
+// unionRect returns the smallest rectangle that contains both a and b,
+// ignoring a when it is empty.
+func unionRect(a, b fixed.Rectangle26_6) fixed.Rectangle26_6 {
+	if a.Min.X >= a.Max.X || a.Min.Y >= a.Max.Y {
+		return b
+	}
+	if b.Min.X < a.Min.X {
+		a.Min.X = b.Min.X
+	}
+	if b.Min.Y < a.Min.Y {
+		a.Min.Y = b.Min.Y
+	}
+	if b.Max.X > a.Max.X {
+		a.Max.X = b.Max.X
+	}
+	if b.Max.Y > a.Max.Y {
+		a.Max.Y = b.Max.Y
+	}
+	return a
+}
+
+// getFace takes a face of the given font and size for the exclusive use of
+// the caller, who puts it back into g.pool(k) when done.
+func (g *drawer) getFace(k faceKey) font.Face {
+	return g.pool(k).Get().(font.Face)
+}
+
+// pool returns the pool of idle faces of the given font and size, creating
+// it on first use. Beyond maxFacePools pools, an arbitrary one is dropped;
+// its faces in use are put back into a new pool.
+func (g *drawer) pool(k faceKey) *sync.Pool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.faces[k]
+	if !ok {
+		if len(g.faces) >= maxFacePools {
+			for old := range g.faces {
+				delete(g.faces, old)
+				break
+			}
+		}
+		ttf := g.fonts[k.font].ttf
+		p = &sync.Pool{New: func() interface{} {
+			return truetype.NewFace(ttf, &truetype.Options{
+				Size:              k.size,
+				DPI:               g.dpi,
+				Hinting:           g.fontHinting,
+				GlyphCacheEntries: glyphCacheEntries(ttf, k.size, g.dpi),
+			})
+		}}
+		g.faces[k] = p
+	}
+	return p
+}
+
+// maxGlyphCache bounds the bytes of the glyph cache of a face.
+const maxGlyphCache = 4 << 20
+
+// glyphCacheEntries returns the number of entries of the glyph cache of a face
+// of ttf at the given size. Each entry holds a mask as large as the biggest
+// glyph of the font, so the 512 entries of truetype would take gigabytes at
+// the largest sizes.
+func glyphCacheEntries(ttf *truetype.Font, size, dpi float64) int {
+	b := ttf.Bounds(fixed.Int26_6(0.5 + size*dpi*64/72))
+	mask := (b.Max.X - b.Min.X + 63).Ceil() * (b.Max.Y - b.Min.Y + 63).Ceil()
+	n := 512
+	for n > 1 && n*mask > maxGlyphCache {
+		n /= 2
+	}
+	return n
+}
+
+// autoFontSize returns the font size of n initials in a box of the given
+// side length. It is rounded to whole pixels so that faces are shared between
+// similar sizes.
+func (g *drawer) autoFontSize(box, n int) float64 {
+	scale := 1.0
+	if len(g.fontScales) > 0 {
+		if n < 1 {
+			n = 1
+		}
+		if n > len(g.fontScales) {
+			n = len(g.fontScales)
+		}
+		scale = g.fontScales[n-1]
+	}
+	size := math.Floor(float64(box)*g.fontRatio*scale + 0.5)
+	if size < 1 {
+		size = 1
+	}
+	if size > maxSize {
+		size = maxSize
+	}
+	return size
 }
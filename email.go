@@ -0,0 +1,73 @@
+package avatar
+
+import (
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// parseMailbox parses s as an RFC 5322 mailbox, an email address with an
+// optional display name such as `"Doe, John" <john@example.com>` or
+// `Jane <jane@bücher.de>`. Display names with unquoted specials, which mail
+// clients often produce, and internationalized domain names are accepted.
+func parseMailbox(s string) (*mail.Address, bool) {
+	if !strings.Contains(s, "@") {
+		return nil, false
+	}
+	a, err := mail.ParseAddress(s)
+	if err != nil {
+		// Doe, John <john@example.com>
+		lt := strings.LastIndex(s, "<")
+		if lt < 0 || !strings.HasSuffix(s, ">") {
+			return nil, false
+		}
+		a, err = mail.ParseAddress(s[lt:])
+		if err != nil {
+			return nil, false
+		}
+		a.Name = strings.Trim(strings.TrimSpace(s[:lt]), `"`)
+	}
+
+	at := strings.LastIndex(a.Address, "@")
+	if at <= 0 {
+		return nil, false
+	}
+	domain, err := idna.ToASCII(a.Address[at+1:])
+	if err != nil || !isDomainName(domain) {
+		return nil, false
+	}
+	return a, true
+}
+
+// localPart returns the part before the @ of w when w is an email address
+// without a display name.
+func localPart(w string) (string, bool) {
+	a, ok := parseMailbox(w)
+	if !ok || a.Name != "" {
+		return "", false
+	}
+	return a.Address[:strings.LastIndex(a.Address, "@")], true
+}
+
+// isDomainName reports whether s is a host name in ASCII of at least two
+// labels, the last one not numeric.
+func isDomainName(s string) bool {
+	labels := strings.Split(strings.TrimSuffix(s, "."), ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, l := range labels {
+		if l == "" || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(l); i++ {
+			c := l[i]
+			if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	tld := labels[len(labels)-1]
+	return strings.Trim(tld, "0123456789") != ""
+}
@@ -0,0 +1,113 @@
+package avatar
+
+import "testing"
+
+func TestParseMailbox(t *testing.T) {
+	stuffs := []struct {
+		mailbox string
+		ok      bool
+		name    string
+		address string
+	}{
+		{"joe@example.com", true, "", "joe@example.com"},
+		{"<joe@example.com>", true, "", "joe@example.com"},
+		{"Joe <joe@example.com>", true, "Joe", "joe@example.com"},
+		{`"Doe, John" <john@example.com>`, true, "Doe, John", "john@example.com"},
+		{"Doe, John <john@example.com>", true, "Doe, John", "john@example.com"},
+		{"Jane <jane@bücher.de>", true, "Jane", "jane@bücher.de"},
+		{"=?utf-8?q?J=C3=B6rg_M=C3=BCller?= <jm@example.com>", true, "Jörg Müller", "jm@example.com"},
+		{"joe@xn--bcher-kva.de", true, "", "joe@xn--bcher-kva.de"},
+		{"joe", false, "", ""},
+		{"joe@localhost", false, "", ""},
+		{"a@b.123", false, "", ""},
+		{"joe@-example.com", false, "", ""},
+		{"joe@exa_mple.com", false, "", ""},
+		{"joe@@example.com", false, "", ""},
+		{"Joe <joe@example.com", false, "", ""},
+		{"@example.com", false, "", ""},
+	}
+
+	for _, v := range stuffs {
+		a, ok := parseMailbox(v.mailbox)
+		if ok != v.ok {
+			t.Errorf("%s: expected %v got %v", v.mailbox, v.ok, ok)
+			continue
+		}
+		if ok && (a.Name != v.name || a.Address != v.address) {
+			t.Errorf("%s: expected %q %q got %q %q", v.mailbox, v.name, v.address, a.Name, a.Address)
+		}
+	}
+}
+
+func TestIsDomainName(t *testing.T) {
+	stuffs := []struct {
+		domain string
+		ok     bool
+	}{
+		{"example.com", true},
+		{"mail.example.co.uk", true},
+		{"example.com.", true},
+		{"xn--bcher-kva.de", true},
+		{"123.example.com", true},
+		{"localhost", false},
+		{"example..com", false},
+		{"example.123", false},
+		{"exa mple.com", false},
+		{"-example.com", false},
+		{"example-.com", false},
+	}
+
+	for _, v := range stuffs {
+		if ok := isDomainName(v.domain); ok != v.ok {
+			t.Errorf("%s: expected %v got %v", v.domain, v.ok, ok)
+		}
+	}
+}
+
+func TestParseInitialsMailbox(t *testing.T) {
+	stuffs := []struct {
+		name     string
+		opts     InitialsOptions
+		initials string
+	}{
+		{`"Doe, John" <john@example.com>`, InitialsOptions{}, "JD"},
+		{"Doe, John <john@example.com>", InitialsOptions{}, "JD"},
+		{"Jane <jane@bücher.de>", InitialsOptions{}, "J"},
+		{"jane@bücher.de", InitialsOptions{}, "j"},
+		{"<joe@example.com>", InitialsOptions{}, "j"},
+		{"=?utf-8?q?J=C3=B6rg_M=C3=BCller?= <jm@example.com>", InitialsOptions{}, "JM"},
+		{"Jane <jane.doe@example.com>", InitialsOptions{SplitHandles: true}, "J"},
+		{"<jane.doe@example.com>", InitialsOptions{SplitHandles: true}, "JD"},
+		{"joe@localhost", InitialsOptions{}, "j"},
+		{"Smith, John", InitialsOptions{}, "JS"},
+		{"Smith, John Ronald", InitialsOptions{}, "JRS"},
+		{"Smith, John, Jr.", InitialsOptions{}, "JS"},
+		{"Smith, John Jr.", InitialsOptions{}, "JS"},
+		{"John Smith, Jr.", InitialsOptions{}, "JS"},
+		{"John Smith, PhD", InitialsOptions{}, "JS"},
+		{"Smith,", InitialsOptions{}, "S"},
+		{"Acme, Inc.", InitialsOptions{}, "A"},
+		{"Acme Widgets, LLC", InitialsOptions{}, "AW"},
+		{"John Smith, CEO", InitialsOptions{}, "JSC"},
+		{"SMITH, JOHN", InitialsOptions{}, "JS"},
+	}
+
+	for _, v := range stuffs {
+		initials, err := ParseInitials(v.name, v.opts)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", v.name, err)
+		}
+		if initials != v.initials {
+			t.Errorf("%s: expected %q got %q", v.name, v.initials, initials)
+		}
+	}
+}
+
+func TestInitialsAvatar_DrawMailbox(t *testing.T) {
+	av := New(testFontFile())
+	for _, name := range []string{`"Doe, John" <john@example.com>`, "<jane@bücher.de>"} {
+		if _, err := av.DrawToBytes(name, defaultSize); err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
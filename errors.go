@@ -0,0 +1,70 @@
+package avatar
+
+import "fmt"
+
+// Drawing an avatar fails with one of these errors, or with the sentinel
+// errors of the options and a *FontError:
+//
+//	*EmptyNameError         the name has nothing but spaces
+//	*UnsupportedCharError   the name has no character taken as an initial
+//	*MissingGlyphError      no font has a glyph for an initial
+//	*InvalidSizeError       the image size is negative or above 4096
+//	*UnsupportedFormatError the image format is not supported
+//
+// Use errors.As to get them. The former sentinel errors match them with
+// errors.Is.
+
+// EmptyNameError is returned when the name is empty or only spaces and no
+// InitialsOptions.Fallback is set.
+type EmptyNameError struct{}
+
+func (e *EmptyNameError) Error() string { return "avatar: empty name" }
+
+// UnsupportedCharError is returned when the name has no character taken as
+// an initial and no InitialsOptions.Fallback is set. Rune is the first
+// character of the name that was rejected, spaces and punctuation aside.
+type UnsupportedCharError struct {
+	Rune rune
+}
+
+func (e *UnsupportedCharError) Error() string {
+	return fmt.Sprintf("avatar: unsupported character %q", e.Rune)
+}
+
+// Is reports whether target is ErrUnsupportChar.
+func (e *UnsupportedCharError) Is(target error) bool { return target == ErrUnsupportChar }
+
+// MissingGlyphError is returned when none of the fonts has a glyph for Rune.
+type MissingGlyphError struct {
+	Rune rune
+}
+
+func (e *MissingGlyphError) Error() string {
+	return fmt.Sprintf("avatar: no font has a glyph for %q", e.Rune)
+}
+
+// InvalidSizeError is returned when the image size is negative or larger
+// than 4096 pixels.
+type InvalidSizeError struct {
+	Size int
+}
+
+func (e *InvalidSizeError) Error() string {
+	return fmt.Sprintf("avatar: invalid size %d", e.Size)
+}
+
+// Is reports whether target is ErrInvalidSize.
+func (e *InvalidSizeError) Is(target error) bool { return target == ErrInvalidSize }
+
+// UnsupportedFormatError is returned when the image format is not one of
+// FormatPNG, FormatJPEG and FormatSVG.
+type UnsupportedFormatError struct {
+	Format string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("avatar: unsupported format %q", e.Format)
+}
+
+// Is reports whether target is ErrUnsupportedEncoding.
+func (e *UnsupportedFormatError) Is(target error) bool { return target == ErrUnsupportedEncoding }
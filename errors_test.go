@@ -0,0 +1,44 @@
+package avatar
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	av := New(testFontFile())
+
+	stuffs := []struct {
+		name     string
+		opts     DrawOptions
+		err      error
+		sentinel error
+	}{
+		{"", DrawOptions{}, &EmptyNameError{}, nil},
+		{" \t\n", DrawOptions{}, &EmptyNameError{}, nil},
+		{"\u200f", DrawOptions{}, &EmptyNameError{}, nil},
+		{"***", DrawOptions{}, &UnsupportedCharError{Rune: '*'}, ErrUnsupportChar},
+		{" 42", DrawOptions{}, &UnsupportedCharError{Rune: '4'}, ErrUnsupportChar},
+		{"(42", DrawOptions{}, &UnsupportedCharError{Rune: '4'}, ErrUnsupportChar},
+		{"\U00017000", DrawOptions{}, &MissingGlyphError{Rune: 0x17000}, nil},
+		{"John", DrawOptions{Size: -1}, &InvalidSizeError{Size: -1}, ErrInvalidSize},
+		{"John", DrawOptions{Size: 4097}, &InvalidSizeError{Size: 4097}, ErrInvalidSize},
+		{"John", DrawOptions{Format: "gif"}, &UnsupportedFormatError{Format: "gif"}, ErrUnsupportedEncoding},
+	}
+
+	for _, v := range stuffs {
+		_, err := av.DrawBytes(v.name, v.opts)
+		if !reflect.DeepEqual(err, v.err) {
+			t.Errorf("%q: expected %v got %v", v.name, v.err, err)
+		}
+		if v.sentinel != nil && !errors.Is(err, v.sentinel) {
+			t.Errorf("%q: expected %v to match %v", v.name, err, v.sentinel)
+		}
+	}
+
+	var e *UnsupportedCharError
+	if _, err := av.Draw("?!", DrawOptions{}); !errors.As(err, &e) || e.Rune != '?' {
+		t.Errorf("expected an *UnsupportedCharError for '?' got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package avatar
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+type fileCache struct {
+	counters
+	dir string
+}
+
+// NewFileCache returns a cache that stores every value in a file of dir, so
+// that it survives restarts and can be shared by several processes. The
+// directory is created if needed. Nothing is ever removed but by Delete.
+func NewFileCache(dir string) (Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &fileCache{dir: dir}, nil
+}
+
+// path returns the file of key, named by its digest so that any key maps to
+// its own file in c.dir.
+func (c *fileCache) path(key string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%x", sha1.Sum([]byte(key))))
+}
+
+func (c *fileCache) Get(key string) ([]byte, bool) {
+	v, err := ioutil.ReadFile(c.path(key))
+	ok := err == nil
+	c.count(ok)
+	return v, ok
+}
+
+// Set writes value to a temporary file first, so that readers never see a
+// partial value.
+func (c *fileCache) Set(key string, value []byte) {
+	f, err := ioutil.TempFile(c.dir, ".tmp-")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(value)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+func (c *fileCache) Delete(key string) { os.Remove(c.path(key)) }
+
+func (c *fileCache) Stats() CacheStats {
+	s := c.stats()
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return s
+	}
+	for _, fi := range files {
+		if fi.Mode().IsRegular() && fi.Name()[0] != '.' {
+			s.Items++
+			s.Bytes += fi.Size()
+		}
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package avatar
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// errRenderPanicked is returned to the callers waiting on a render that
+// panicked.
+var errRenderPanicked = errors.New("avatar: render panicked")
+
+// flightCall is a render in progress or completed.
+type flightCall struct {
+	wg sync.WaitGroup
+
+	data []byte
+	err  error
+}
+
+// flightGroup deduplicates concurrent renders of the same key: the first
+// caller renders while the others wait for and share its result.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+
+	renders   uint64 // fn calls
+	coalesced uint64 // calls that shared another call's render
+}
+
+// do calls fn once for all the concurrent calls with the same key and
+// returns its result to each of them.
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		atomic.AddUint64(&g.coalesced, 1)
+		c.wg.Wait()
+		return c.data, c.err
+	}
+	c := new(flightCall)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// release the waiters even if fn panics
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	atomic.AddUint64(&g.renders, 1)
+	c.err = errRenderPanicked
+	c.data, c.err = fn()
+	return c.data, c.err
+}
@@ -0,0 +1,163 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// waitCoalesced blocks until a render of key is in progress and n calls have
+// shared the renders of g.
+func waitCoalesced(g *flightGroup, key string, n uint64) {
+	for {
+		g.mu.Lock()
+		_, ok := g.calls[key]
+		g.mu.Unlock()
+		if ok && atomic.LoadUint64(&g.coalesced) == n {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestFlightGroup(t *testing.T) {
+	stuffs := []struct {
+		data []byte
+		err  error
+	}{
+		{[]byte("avatar"), nil},
+		{nil, errors.New("failed")},
+	}
+
+	for _, v := range stuffs {
+		var g flightGroup
+		const n = 8
+		release := make(chan struct{})
+		calls := 0
+		fn := func() ([]byte, error) {
+			calls++
+			<-release
+			return v.data, v.err
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				data, err := g.do("key", fn)
+				if !bytes.Equal(data, v.data) || err != v.err {
+					t.Errorf("expected %q, %v got %q, %v", v.data, v.err, data, err)
+				}
+			}()
+		}
+		waitCoalesced(&g, "key", n-1)
+		close(release)
+		wg.Wait()
+
+		if calls != 1 {
+			t.Errorf("expected 1 call got %d", calls)
+		}
+		if g.renders != 1 || g.coalesced != n-1 {
+			t.Errorf("expected 1 render and %d coalesced got %d and %d", n-1, g.renders, g.coalesced)
+		}
+		if len(g.calls) != 0 {
+			t.Errorf("expected no call in progress got %d", len(g.calls))
+		}
+	}
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	var g flightGroup
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		defer func() { recover() }()
+		g.do("key", func() ([]byte, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+	waitCoalesced(&g, "key", 0)
+	go func() {
+		_, err := g.do("key", nil)
+		done <- err
+	}()
+	waitCoalesced(&g, "key", 1)
+	close(release)
+	if err := <-done; err != errRenderPanicked {
+		t.Errorf("expected %v got %v", errRenderPanicked, err)
+	}
+}
+
+// blockingCache is a cache whose Set waits for release, holding the first
+// render of a key in progress.
+type blockingCache struct {
+	Cache
+	release chan struct{}
+}
+
+func (c *blockingCache) Set(key string, value []byte) {
+	<-c.release
+	c.Cache.Set(key, value)
+}
+
+func TestInitialsAvatar_DrawBytesCoalesce(t *testing.T) {
+	cache := &blockingCache{NewLRUCache(0, 0), make(chan struct{})}
+	av, err := NewWithConfigE(Config{
+		FontFile: testFontFile(),
+		Cache:    cache,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DrawOptions{Size: 64}
+	const n = 16
+	results := make([][]byte, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			results[i], err = av.DrawBytes("Condor Heroes", opts)
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+
+	o := opts
+	initials, err := av.prepare("Condor Heroes", &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitCoalesced(&av.flight, newCacheKey(initials, av.drawer.fontID, av.imageID, &o).String(), n-1)
+	close(cache.release)
+	wg.Wait()
+
+	for i := 1; i < n; i++ {
+		if !bytes.Equal(results[i], results[0]) {
+			t.Fatalf("call %d got a different image", i)
+		}
+	}
+	stats := av.Stats()
+	if stats.Renders != 1 || stats.Coalesced != n-1 {
+		t.Errorf("expected 1 render and %d coalesced got %d and %d", n-1, stats.Renders, stats.Coalesced)
+	}
+	if stats.Cache.Misses != n || stats.Cache.Items != 1 {
+		t.Errorf("expected %d misses and 1 item got %+v", n, stats.Cache)
+	}
+
+	// later calls hit the cache
+	if _, err := av.DrawBytes("Condor Heroes", opts); err != nil {
+		t.Fatal(err)
+	}
+	if stats := av.Stats(); stats.Renders != 1 || stats.Cache.Hits != 1 {
+		t.Errorf("expected 1 render and 1 hit got %+v", stats)
+	}
+}
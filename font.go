@@ -0,0 +1,107 @@
+package avatar
+
+import (
+	"crypto/sha1"
+	_ "embed" // for the default font
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/golang/freetype/truetype"
+)
+
+var (
+	// ErrFontRequired is returned when no font is given.
+	ErrFontRequired = errors.New("font file is required")
+
+	// ErrInvalidFont matches every *FontError with errors.Is.
+	ErrInvalidFont = errors.New("invalid font")
+)
+
+// FontError is returned when a font cannot be read or parsed, or when a
+// glyph of it cannot be loaded.
+type FontError struct {
+	File string // font file path, empty for fonts not read from a file
+	Err  error  // underlying io or truetype error
+}
+
+func (e *FontError) Error() string {
+	if e.File == "" {
+		return "invalid font: " + e.Err.Error()
+	}
+	return "invalid font " + e.File + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying io or truetype error.
+func (e *FontError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrInvalidFont.
+func (e *FontError) Is(target error) bool { return target == ErrInvalidFont }
+
+// DejaVu Sans, see resource/fonts/DejaVuSans-LICENSE.txt.
+//
+//go:embed resource/fonts/DejaVuSans.ttf
+var defaultFontData []byte
+
+var (
+	defaultFont     *Font
+	defaultFontOnce sync.Once
+)
+
+// Font is a parsed TrueType font.
+type Font struct {
+	ttf *truetype.Font
+	id  string // digest of the font data
+}
+
+// DefaultFont returns the font compiled into the package, DejaVu Sans. It is
+// used when no font is configured.
+func DefaultFont() *Font {
+	defaultFontOnce.Do(func() {
+		f, err := ParseFont(defaultFontData)
+		if err != nil {
+			panic(err.Error())
+		}
+		defaultFont = f
+	})
+	return defaultFont
+}
+
+// ParseFont parses TrueType font data. The error is a *FontError.
+func ParseFont(data []byte) (*Font, error) {
+	ttf, err := truetype.Parse(data)
+	if err != nil {
+		return nil, &FontError{Err: err}
+	}
+	return &Font{
+		ttf: ttf,
+		id:  fmt.Sprintf("%x", sha1.Sum(data)),
+	}, nil
+}
+
+// ReadFont reads and parses TrueType font data from r. The error is a
+// *FontError.
+func ReadFont(r io.Reader) (*Font, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, &FontError{Err: err}
+	}
+	return ParseFont(data)
+}
+
+// parseFont parse the font file as *Font (TTF)
+func parseFont(fontFile string) (*Font, error) {
+	fontBytes, err := ioutil.ReadFile(fontFile)
+	if err != nil {
+		return nil, &FontError{File: fontFile, Err: err}
+	}
+
+	font, err := ParseFont(fontBytes)
+	if err != nil {
+		return nil, &FontError{File: fontFile, Err: err.(*FontError).Err}
+	}
+
+	return font, nil
+}
@@ -0,0 +1,184 @@
+//go:build go1.18
+// +build go1.18
+
+package avatar
+
+import (
+	"bytes"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Fuzzing needs Go 1.18:
+//
+//	go test -run XXX -fuzz FuzzParseInitials
+//	go test -run XXX -fuzz FuzzInitialsAvatar_DrawBytes
+//
+// Inputs that failed are kept in testdata/fuzz and run by go test, next to
+// hand-written seeds named seed-*.
+
+// fuzzNames is the seed corpus: real-world names from many scripts and the
+// shapes names come in.
+var fuzzNames = []string{
+	// Latin
+	"John Doe", "john doe", "  John   Doe  ", "John Ronald Reuel Tolkien",
+	"Dr. Jean-Luc Picard Jr.", "Ludwig van Beethoven", "Charles de Gaulle",
+	"José María Aznar", "Zoë Saldaña", "Nguyễn Văn An", "Đặng Thị Thu",
+	"Søren Kierkegaard", "Łukasz Żółć", "Dvořák Antonín", "Ólafur Arnalds",
+	"Mary-Kate O'Brien", "D'Angelo", "Jean-Paul Sartre", "Smith, John",
+	"John Smith, Jr.", "Smith, John, PhD", "John Doe (dj)", "John (", "(", ")",
+	// Greek, Cyrillic, Armenian, Georgian
+	"Νίκος Καζαντζάκης", "Фёдор Достоевский", "Лев Николаевич Толстой",
+	"Արամ Խաչատրյան", "შოთა რუსთაველი",
+	// Arabic, Hebrew, Persian, Urdu
+	"محمد علي", "عبد الله بن سعود", "الشيخ زايد", "דוד בן גוריון",
+	"שמעון פרס", "\u200fدانا\u200f", "Dana דנה", "فاطمه زهرا", "عمران خان",
+	// Indic, Thai, Lao, Khmer, Burmese, Tibetan, Sinhala
+	"अमिताभ बच्चन", "স্বামী বিবেকানন্দ", "ਗੁਰੂ ਨਾਨਕ", "સરદાર પટેલ",
+	"ரஜினிகாந்த்", "మహేష్ బాబు", "ಕುವೆಂಪು", "മോഹൻലാൽ", "เสกสรร ประเสริฐ",
+	"ສີສະຫວ່າງ", "សម្តេច ហ៊ុន", "အောင်ဆန်းစုကြည်", "བསྟན་འཛིན་", "මහින්ද",
+	// Chinese, Japanese, Korean
+	"孔子", "王小明", "欧阳修", "司马迁", "諸葛亮", "山田太郎", "佐藤さくら",
+	"やまだ たろう", "ヤマダ", "김민준", "남궁 민수", "선우용여",
+	// Ethiopic, Cherokee, Canadian syllabics, Mongolian
+	"ኃይለ ሥላሴ", "ᏎᏉᏯ", "ᐃᓄᒃᑎᑐᑦ", "ᠴᠢᠩᠭᠢᠰ",
+	// email addresses, mailboxes and user names
+	"joe@example.com", "john.doe@example.com", "jane@bücher.de",
+	`"Doe, John" <john@example.com>`, "Doe, John <john@example.com>",
+	"=?utf-8?q?J=C3=B6rg_M=C3=BCller?= <jm@example.com>", "<@>", "@", "a@b",
+	"john_doe42", "JohnDoe", "JSONParser", "xX_gamer_Xx",
+	// digits, symbols, emoji and invisible characters
+	"42 Analytics", "#general", "R&D", "\U0001f680 Launch",
+	"\U0001f469\u200d\U0001f4bb Dev", "\U0001f1eb\U0001f1f7", "1\ufe0f\u20e3",
+	"", " ", "\t\n", "\u200f", "\u0301", "e\u0301", "\xff\xfe", "...", "*",
+}
+
+// fuzzOptions are the initials options every input is tried with.
+var fuzzOptions = []InitialsOptions{
+	{},
+	{Limit: 1, Casing: CasingUpper},
+	{Limit: 2, SkipEmail: true, SplitHandles: true},
+	{CJK: CJKGivenName, Hangul: HangulGivenName},
+	{CJK: CJKSurnameGivenName, Hangul: HangulChoseong},
+	{CJK: CJKSurname, Locale: "fr", SkipParticles: true, KeepTitles: true},
+	{Locale: "ar", KeepHyphens: true, Casing: CasingLower},
+	{Digits: true, Emoji: true, Symbols: "#&"},
+}
+
+func FuzzParseInitials(f *testing.F) {
+	for _, name := range fuzzNames {
+		f.Add(name)
+	}
+
+	f.Fuzz(func(t *testing.T, name string) {
+		for _, o := range fuzzOptions {
+			initials, err := ParseInitials(name, o)
+			if err != nil {
+				t.Fatalf("%q %+v: unexpected error %v", name, o, err)
+			}
+			if !utf8.ValidString(initials) {
+				t.Errorf("%q %+v: invalid UTF-8 %q", name, o, initials)
+			}
+			limit := o.Limit
+			if limit == 0 {
+				limit = defaultLimit
+			}
+			if n := countInitials(initials); n > limit {
+				t.Errorf("%q %+v: %d initials %q over the limit", name, o, n, initials)
+			}
+
+			// surrounding and repeated spaces do not matter
+			spaced := " \t" + strings.Replace(name, " ", "  ", -1) + "\n"
+			if again, _ := ParseInitials(spaced, o); again != initials {
+				t.Errorf("%q %+v: expected %q got %q with spaces", name, o, initials, again)
+			}
+		}
+	})
+}
+
+func FuzzInitialsAvatar_DrawBytes(f *testing.F) {
+	for i, name := range fuzzNames {
+		f.Add(name, i, 0.0, i%4, i%8, i%2 == 0)
+	}
+	f.Add("John Doe", -1, 0.0, 0, 0, false)
+	f.Add("John Doe", maxSize+1, 0.0, 0, 0, true)
+	f.Add("John Doe", 1<<30, 0.0, 0, 0, false)
+	f.Add("W", 64, 20000.0, 0, 0, false)
+	f.Add("W", 64, maxSize+0.5, 1, 0, true)
+	f.Add("W", 64, math.NaN(), 2, 0, false)
+	f.Add("W", 64, 12.0, 42, 0, false)
+	f.Add("W", 64, 12.0, 3, 32, false)
+
+	av := New(testFontFile())
+	f.Fuzz(func(t *testing.T, name string, size int, fontSize float64, shape, padding int, svg bool) {
+		side := size
+		if side == 0 {
+			side = defaultSize
+		}
+		// the option errors expected from the fuzzed values
+		invalid := map[error]bool{
+			ErrInvalidFontSize:  !(fontSize >= 0 && fontSize <= maxSize),
+			ErrUnsupportedShape: shape < 0 || shape > int(ShapeSquircle),
+			ErrInvalidPadding:   padding < 0 || padding >= side-padding,
+		}
+
+		for _, o := range fuzzOptions {
+			opts := DrawOptions{
+				Size:            size,
+				FontSize:        fontSize,
+				Shape:           Shape(shape),
+				Padding:         padding,
+				InitialsOptions: o,
+			}
+			if svg {
+				opts.Format = FormatSVG
+			}
+			data, err := av.DrawBytes(name, opts)
+			switch err.(type) {
+			case nil:
+			case *EmptyNameError, *UnsupportedCharError, *MissingGlyphError:
+				continue
+			case *InvalidSizeError:
+				if size < 0 || size > maxSize {
+					continue
+				}
+				t.Fatalf("%q %+v: unexpected error %v", name, opts, err)
+			default:
+				if invalid[err] {
+					continue
+				}
+				t.Fatalf("%q %+v: unexpected error %v", name, opts, err)
+			}
+
+			if svg {
+				if !bytes.HasPrefix(data, []byte("<svg")) || !utf8.Valid(data) {
+					t.Errorf("%q %+v: invalid svg document", name, opts)
+				}
+				continue
+			}
+			m, _, err := image.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%q %+v: %v", name, opts, err)
+			}
+			if b := m.Bounds(); b.Dx() != side || b.Dy() != side {
+				t.Errorf("%q %+v: expected %dx%d got %v", name, opts, side, side, b)
+			}
+		}
+	})
+}
+
+// countInitials returns the number of initials in s, each the first grapheme
+// cluster of a word.
+func countInitials(s string) int {
+	n := 0
+	for s != "" {
+		s = s[len(firstGrapheme(s)):]
+		n++
+	}
+	return n
+}
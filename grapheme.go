@@ -0,0 +1,284 @@
+package avatar
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// gcb is the Grapheme_Cluster_Break property of a rune, see
+// https://www.unicode.org/reports/tr29/.
+type gcb int
+
+const (
+	gcbOther gcb = iota
+	gcbCR
+	gcbLF
+	gcbControl
+	gcbExtend
+	gcbZWJ
+	gcbRegionalIndicator
+	gcbPrepend
+	gcbSpacingMark
+	gcbL
+	gcbV
+	gcbT
+	gcbLV
+	gcbLVT
+)
+
+// prepend are the runes of Grapheme_Cluster_Break=Prepend other than the
+// prepended concatenation marks.
+var prepend = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x0d4e, 0x0d4e, 1},
+	},
+	R32: []unicode.Range32{
+		{0x111c2, 0x111c3, 1},
+		{0x1193f, 0x1193f, 1},
+		{0x11941, 0x11941, 1},
+		{0x11a3a, 0x11a3a, 1},
+		{0x11a84, 0x11a89, 1},
+		{0x11d46, 0x11d46, 1},
+	},
+}
+
+// extendedPictographic approximates the Extended_Pictographic property, the
+// runes that start emoji sequences.
+var extendedPictographic = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x00a9, 0x00ae, 5},
+		{0x203c, 0x2049, 13},
+		{0x2122, 0x2139, 23},
+		{0x2194, 0x2199, 1},
+		{0x21a9, 0x21aa, 1},
+		{0x231a, 0x231b, 1},
+		{0x2328, 0x2328, 1},
+		{0x2388, 0x2388, 1},
+		{0x23cf, 0x23cf, 1},
+		{0x23e9, 0x23f3, 1},
+		{0x23f8, 0x23fa, 1},
+		{0x24c2, 0x24c2, 1},
+		{0x25aa, 0x25ab, 1},
+		{0x25b6, 0x25c0, 10},
+		{0x25fb, 0x25fe, 1},
+		{0x2600, 0x2605, 1},
+		{0x2607, 0x2612, 1},
+		{0x2614, 0x2685, 1},
+		{0x2690, 0x2705, 1},
+		{0x2708, 0x2712, 1},
+		{0x2714, 0x2716, 2},
+		{0x271d, 0x2721, 4},
+		{0x2728, 0x2733, 11},
+		{0x2734, 0x2744, 16},
+		{0x2747, 0x274c, 5},
+		{0x274e, 0x2753, 5},
+		{0x2754, 0x2755, 1},
+		{0x2757, 0x2757, 1},
+		{0x2763, 0x2767, 1},
+		{0x2795, 0x2797, 1},
+		{0x27a1, 0x27b0, 15},
+		{0x27bf, 0x27bf, 1},
+		{0x2934, 0x2935, 1},
+		{0x2b05, 0x2b07, 1},
+		{0x2b1b, 0x2b1c, 1},
+		{0x2b50, 0x2b55, 5},
+		{0x3030, 0x303d, 13},
+		{0x3297, 0x3299, 2},
+	},
+	LatinOffset: 1,
+	R32: []unicode.Range32{
+		{0x1f000, 0x1f0ff, 1},
+		{0x1f10d, 0x1f10f, 1},
+		{0x1f12f, 0x1f12f, 1},
+		{0x1f16c, 0x1f171, 1},
+		{0x1f17e, 0x1f17f, 1},
+		{0x1f18e, 0x1f18e, 1},
+		{0x1f191, 0x1f19a, 1},
+		{0x1f1ad, 0x1f1e5, 1},
+		{0x1f201, 0x1f20f, 1},
+		{0x1f21a, 0x1f22f, 21},
+		{0x1f232, 0x1f23a, 1},
+		{0x1f23c, 0x1f23f, 1},
+		{0x1f249, 0x1f3fa, 1},
+		{0x1f400, 0x1f53d, 1},
+		{0x1f546, 0x1f64f, 1},
+		{0x1f680, 0x1f6ff, 1},
+		{0x1f774, 0x1f77f, 1},
+		{0x1f7d5, 0x1f7ff, 1},
+		{0x1f80c, 0x1f80f, 1},
+		{0x1f848, 0x1f84f, 1},
+		{0x1f85a, 0x1f85f, 1},
+		{0x1f888, 0x1f88f, 1},
+		{0x1f8ae, 0x1f8ff, 1},
+		{0x1f90c, 0x1f93a, 1},
+		{0x1f93c, 0x1f945, 1},
+		{0x1f947, 0x1faff, 1},
+		{0x1fc00, 0x1fffd, 1},
+	},
+}
+
+// viramas are the linkers of Indic conjuncts (Indic_Conjunct_Break=Linker).
+var viramas = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x094d, 0x09cd, 0x80},
+		{0x0acd, 0x0b4d, 0x80},
+		{0x0c4d, 0x0d4d, 0x100},
+	},
+}
+
+// gcbOf returns the Grapheme_Cluster_Break property of r.
+func gcbOf(r rune) gcb {
+	switch {
+	case r == '\r':
+		return gcbCR
+	case r == '\n':
+		return gcbLF
+	case r == 0x200d:
+		return gcbZWJ
+	case r == 0x200c, 0x1f3fb <= r && r <= 0x1f3ff,
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Other_Grapheme_Extend):
+		return gcbExtend
+	case unicode.In(r, unicode.Prepended_Concatenation_Mark, prepend):
+		return gcbPrepend
+	case unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp):
+		return gcbControl
+	case 0x1f1e6 <= r && r <= 0x1f1ff:
+		return gcbRegionalIndicator
+	case unicode.Is(unicode.Mc, r), r == 0x0e33, r == 0x0eb3:
+		return gcbSpacingMark
+	case 0x1100 <= r && r <= 0x115f, 0xa960 <= r && r <= 0xa97c:
+		return gcbL
+	case 0x1160 <= r && r <= 0x11a7, 0xd7b0 <= r && r <= 0xd7c6:
+		return gcbV
+	case 0x11a8 <= r && r <= 0x11ff, 0xd7cb <= r && r <= 0xd7fb:
+		return gcbT
+	case hangulBase <= r && r <= hangulLast:
+		if (r-hangulBase)%28 == 0 {
+			return gcbLV
+		}
+		return gcbLVT
+	}
+	return gcbOther
+}
+
+// isConjunctConsonant approximates Indic_Conjunct_Break=Consonant: the
+// letters of the scripts whose viramas form conjuncts.
+func isConjunctConsonant(r rune) bool {
+	return unicode.Is(unicode.Lo, r) && unicode.In(r, unicode.Devanagari,
+		unicode.Bengali, unicode.Gujarati, unicode.Oriya, unicode.Telugu, unicode.Malayalam)
+}
+
+// nextGrapheme returns the length in bytes of the first extended grapheme
+// cluster of s.
+func nextGrapheme(s string) int {
+	r, n := utf8.DecodeRuneInString(s)
+	if n == 0 {
+		return 0
+	}
+	prev := gcbOf(r)
+
+	// state of the rules that look further back than the previous rune
+	ri := prev == gcbRegionalIndicator          // odd number of regional indicators
+	pict := unicode.Is(extendedPictographic, r) // pictograph then Extend*
+	zwj := false                                // pictograph then Extend* ZWJ
+	conjunct := 0                               // 1 after a consonant, 2 after a linker too
+	if isConjunctConsonant(r) {
+		conjunct = 1
+	}
+
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		cur := gcbOf(r)
+
+		var join bool
+		switch {
+		case prev == gcbCR && cur == gcbLF: // GB3
+			join = true
+		case prev == gcbCR || prev == gcbLF || prev == gcbControl: // GB4
+		case cur == gcbCR || cur == gcbLF || cur == gcbControl: // GB5
+		case prev == gcbL && (cur == gcbL || cur == gcbV || cur == gcbLV || cur == gcbLVT): // GB6
+			join = true
+		case (prev == gcbLV || prev == gcbV) && (cur == gcbV || cur == gcbT): // GB7
+			join = true
+		case (prev == gcbLVT || prev == gcbT) && cur == gcbT: // GB8
+			join = true
+		case cur == gcbExtend || cur == gcbZWJ || cur == gcbSpacingMark: // GB9, GB9a
+			join = true
+		case prev == gcbPrepend: // GB9b
+			join = true
+		case conjunct == 2 && isConjunctConsonant(r): // GB9c
+			join = true
+		case zwj && unicode.Is(extendedPictographic, r): // GB11
+			join = true
+		case prev == gcbRegionalIndicator && cur == gcbRegionalIndicator && ri: // GB12, GB13
+			join = true
+		}
+		if !join {
+			break
+		}
+
+		ri = cur == gcbRegionalIndicator && !ri
+		zwj = cur == gcbZWJ && pict
+		switch {
+		case unicode.Is(extendedPictographic, r):
+			pict = true
+		case cur != gcbExtend:
+			pict = false
+		}
+		switch {
+		case isConjunctConsonant(r):
+			conjunct = 1
+		case unicode.Is(viramas, r):
+			if conjunct > 0 {
+				conjunct = 2
+			}
+		case cur != gcbExtend && cur != gcbZWJ:
+			conjunct = 0
+		}
+
+		prev = cur
+		n += size
+	}
+	return n
+}
+
+// graphemes splits s into extended grapheme clusters.
+func graphemes(s string) []string {
+	var gs []string
+	for s != "" {
+		n := nextGrapheme(s)
+		gs = append(gs, s[:n])
+		s = s[n:]
+	}
+	return gs
+}
+
+// isEmoji reports whether the grapheme cluster g is an emoji: a pictograph,
+// a flag or a keycap.
+func isEmoji(g string) bool {
+	r, _ := utf8.DecodeRuneInString(g)
+	return unicode.Is(extendedPictographic, r) || gcbOf(r) == gcbRegionalIndicator ||
+		strings.ContainsRune(g, '\u20e3')
+}
+
+// initialsPrefix returns the first n initials of s, each the first grapheme
+// cluster of a word.
+func initialsPrefix(s string, n int) string {
+	i := 0
+	for ; n > 0 && i < len(s); n-- {
+		i += len(firstGrapheme(s[i:]))
+	}
+	return s[:i]
+}
+
+// firstGrapheme returns the first grapheme cluster of the word w. A Thai or
+// Lao vowel written before the consonant it follows in speech is kept with
+// that consonant.
+func firstGrapheme(w string) string {
+	n := nextGrapheme(w)
+	if r, _ := utf8.DecodeRuneInString(w); unicode.Is(unicode.Logical_Order_Exception, r) && n < len(w) {
+		n += nextGrapheme(w[n:])
+	}
+	return w[:n]
+}
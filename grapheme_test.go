@@ -0,0 +1,83 @@
+package avatar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGraphemes(t *testing.T) {
+	stuffs := []struct {
+		s  string
+		gs []string
+	}{
+		{"", nil},
+		{"ab", []string{"a", "b"}},
+		{"e\u0301t", []string{"e\u0301", "t"}},
+		{"\r\n\n", []string{"\r\n", "\n"}},
+		{"क्षमा", []string{"क्ष", "मा"}},
+		{"श्री", []string{"श्री"}},
+		{"शर्मा", []string{"श", "र्मा"}},
+		{"น้ำใจ", []string{"น้ำ", "ใ", "จ"}},
+		{"\u1100\u1161\u11a8가", []string{"\u1100\u1161\u11a8", "가"}},
+		{"👩\u200d💻!", []string{"👩\u200d💻", "!"}},
+		{"👍🏽", []string{"👍🏽"}},
+		{"🇫🇷🇩🇪🇮", []string{"🇫🇷", "🇩🇪", "🇮"}},
+		{"\u0600١", []string{"\u0600١"}},
+	}
+
+	for _, v := range stuffs {
+		if gs := graphemes(v.s); !reflect.DeepEqual(gs, v.gs) {
+			t.Errorf("%+q: expected %+q got %+q", v.s, v.gs, gs)
+		}
+	}
+}
+
+func TestParseInitialsGraphemes(t *testing.T) {
+	stuffs := []struct {
+		name, initials string
+	}{
+		// decomposed Latin
+		{"E\u0301mile Zola", "ÉZ"},
+		{"Émile Zola", "ÉZ"},
+		{"A\u030angstro\u0308m", "Å"},
+		// Vietnamese
+		{"Nguye\u0302\u0303n Va\u0306n A\u0301nh", "NVÁ"},
+		{"Đặng Thị Ngọc Thịnh", "ĐTN"},
+		// Devanagari, Bengali and Tamil
+		{"क्षमा शर्मा", "क्षश"},
+		{"श्री राम", "श्रीरा"},
+		{"স্বাতী রায়", "স্বারা"},
+		{"தமிழ் செல்வன்", "தசெ"},
+		// Thai, with vowels written before their consonant
+		{"สมชาย ใจดี", "สใจ"},
+		{"ฐิติพร เกิดผล", "ฐิเกิ"},
+	}
+
+	for _, v := range stuffs {
+		initials, err := ParseInitials(v.name, InitialsOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if initials != v.initials {
+			t.Errorf("%s: expected %+q got %+q", v.name, v.initials, initials)
+		}
+	}
+}
+
+func TestInitialsAvatar_DrawNFC(t *testing.T) {
+	av := New("")
+	composed, err := av.DrawBytes("Émile Zola", DrawOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decomposed, err := av.DrawBytes("E\u0301mile Zola", DrawOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(composed) != string(decomposed) {
+		t.Error("expected the same image for composed and decomposed names")
+	}
+	if stats := av.Stats(); stats.Renders != 1 {
+		t.Errorf("expected 1 render got %d", stats.Renders)
+	}
+}
@@ -0,0 +1,57 @@
+package avatar
+
+import "unicode"
+
+// splitHandle splits a user name such as john.doe, john_doe, JohnDoe or
+// jdoe42 into its words, breaking on punctuation, digits and camelCase
+// boundaries.
+func splitHandle(h string) []string {
+	rs := []rune(h)
+	var words []string
+	start := -1
+	for i, r := range rs {
+		if !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r) {
+			if start >= 0 {
+				words = append(words, string(rs[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start >= 0 && unicode.IsUpper(r) {
+			prev := rs[i-1]
+			next := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			// johnDoe, or the end of an acronym as in JSONParser
+			if unicode.IsLower(prev) || unicode.IsUpper(prev) && next {
+				words = append(words, string(rs[start:i]))
+				start = i
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(rs[start:]))
+	}
+	return words
+}
+
+// handleWords returns the words of the handle or email address w when
+// o.SplitHandles is set. It reports false when w is neither.
+func handleWords(w string, o InitialsOptions) ([]string, bool) {
+	if !o.SplitHandles {
+		return nil, false
+	}
+	local, email := localPart(w)
+	if email {
+		if o.SkipEmail {
+			return nil, false
+		}
+		w = local
+	}
+	words := splitHandle(w)
+	if len(words) == 0 || !email && len(words) == 1 && words[0] == w {
+		return nil, false
+	}
+	return words, true
+}
@@ -0,0 +1,68 @@
+package avatar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitHandle(t *testing.T) {
+	stuffs := []struct {
+		handle string
+		words  []string
+	}{
+		{"john", []string{"john"}},
+		{"john.doe", []string{"john", "doe"}},
+		{"john_doe", []string{"john", "doe"}},
+		{"john-doe", []string{"john", "doe"}},
+		{"JohnDoe", []string{"John", "Doe"}},
+		{"johnDoe", []string{"john", "Doe"}},
+		{"JSONParser", []string{"JSON", "Parser"}},
+		{"jdoe42", []string{"jdoe"}},
+		{"42jdoe", []string{"jdoe"}},
+		{"john2doe", []string{"john", "doe"}},
+		{"__john__.doe__", []string{"john", "doe"}},
+		{"élodie.durand", []string{"élodie", "durand"}},
+		{"1234", nil},
+	}
+
+	for _, v := range stuffs {
+		if words := splitHandle(v.handle); !reflect.DeepEqual(words, v.words) {
+			t.Errorf("%s: expected %q got %q", v.handle, v.words, words)
+		}
+	}
+}
+
+func TestParseInitialsHandles(t *testing.T) {
+	split := InitialsOptions{SplitHandles: true}
+	stuffs := []struct {
+		name     string
+		opts     InitialsOptions
+		initials string
+	}{
+		{"john.doe@example.com", split, "JD"},
+		{"john.doe@example.com", InitialsOptions{}, "j"},
+		{"joe@example.com", split, "J"},
+		{"john.doe@example.com", InitialsOptions{SplitHandles: true, SkipEmail: true}, ""},
+		{"john.doe", split, "JD"},
+		{"john_doe", split, "JD"},
+		{"JohnDoe", split, "JD"},
+		{"jdoe42", split, "J"},
+		{"john.ronald.reuel.tolkien", split, "JRR"},
+		{"john.doe", InitialsOptions{SplitHandles: true, Casing: CasingLower}, "jd"},
+		{"john.doe", InitialsOptions{}, "j"},
+		// names are not handles
+		{"John", split, "J"},
+		{"john doe", split, "jd"},
+		{"Jean-Luc Picard", split, "JLP"},
+	}
+
+	for _, v := range stuffs {
+		initials, err := ParseInitials(v.name, v.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if initials != v.initials {
+			t.Errorf("%s: expected %q got %q", v.name, v.initials, initials)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package avatar
+
+// HangulMode selects the initials of Korean names.
+type HangulMode int
+
+const (
+	// HangulDefault uses the mode of Config.InitialsOptions, or HangulFirst.
+	HangulDefault HangulMode = iota
+
+	// HangulFirst takes the first syllable of the name, the surname, like
+	// any other word.
+	HangulFirst
+
+	// HangulChoseong takes the initial consonant of every syllable:
+	// 김민준 gives ㄱㅁㅈ.
+	HangulChoseong
+
+	// HangulGivenName takes the last two syllables, the given name of most
+	// Korean names: 김민준 gives 민준. The second word is taken when the
+	// name has a space.
+	HangulGivenName
+)
+
+const (
+	hangulBase  = 0xAC00 // 가
+	hangulLast  = 0xD7A3 // 힣
+	hangulBlock = 21 * 28
+)
+
+// choseong are the initial consonants of the Hangul syllables, in the order
+// of the syllable blocks, as compatibility jamo that stand alone.
+var choseong = []rune("ㄱㄲㄴㄷㄸㄹㅁㅂㅃㅅㅆㅇㅈㅉㅊㅋㅌㅍㅎ")
+
+// decomposeChoseong returns the initial consonant of the Hangul syllable r,
+// or r itself when it is not a syllable.
+func decomposeChoseong(r rune) rune {
+	if r < hangulBase || r > hangulLast {
+		return r
+	}
+	return choseong[(r-hangulBase)/hangulBlock]
+}
+
+// hangulInitials returns the initials of a Korean name split in words
+// according to o.Hangul. It reports false when the name is not one.
+func hangulInitials(words []string, o InitialsOptions) (string, bool) {
+	if o.Hangul == HangulFirst || len(words) == 0 || wordScript(words[0]) != scriptHangul {
+		return "", false
+	}
+
+	var initials []rune
+	switch o.Hangul {
+	case HangulChoseong:
+		for _, w := range words {
+			if wordScript(w) != scriptHangul {
+				break
+			}
+			for _, r := range w {
+				initials = append(initials, decomposeChoseong(r))
+			}
+		}
+	case HangulGivenName:
+		w := words[0]
+		if len(words) > 1 && wordScript(words[1]) == scriptHangul {
+			w = words[1]
+		}
+		initials = []rune(w)
+		if len(initials) > 2 && len(words) == 1 {
+			initials = initials[len(initials)-2:]
+		}
+	}
+	return runePrefix(string(initials), o.Limit), true
+}
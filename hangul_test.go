@@ -0,0 +1,97 @@
+package avatar
+
+import "testing"
+
+func TestDecomposeChoseong(t *testing.T) {
+	stuffs := []struct {
+		r, choseong rune
+	}{
+		{'가', 'ㄱ'},
+		{'김', 'ㄱ'},
+		{'까', 'ㄲ'},
+		{'민', 'ㅁ'},
+		{'준', 'ㅈ'},
+		{'힣', 'ㅎ'},
+		{'ㄱ', 'ㄱ'},
+		{'A', 'A'},
+	}
+
+	for _, v := range stuffs {
+		if got := decomposeChoseong(v.r); got != v.choseong {
+			t.Errorf("%c: expected %c got %c", v.r, v.choseong, got)
+		}
+	}
+}
+
+func TestParseInitialsHangul(t *testing.T) {
+	stuffs := []struct {
+		name     string
+		mode     HangulMode
+		limit    int
+		initials string
+	}{
+		{"김민준", HangulFirst, 0, "김"},
+		{"김민준", HangulChoseong, 0, "ㄱㅁㅈ"},
+		{"김민준", HangulChoseong, 2, "ㄱㅁ"},
+		{"김민준", HangulGivenName, 0, "민준"},
+		{"남궁민수", HangulGivenName, 0, "민수"},
+		{"김 민준", HangulGivenName, 0, "민준"},
+		{"김 민준", HangulChoseong, 0, "ㄱㅁㅈ"},
+		{"민준", HangulGivenName, 0, "민준"},
+		{"준", HangulGivenName, 0, "준"},
+		{"김민준", HangulGivenName, 1, "민"},
+		// other names are not affected
+		{"John Doe", HangulChoseong, 0, "JD"},
+		{"孔子", HangulChoseong, 0, "孔"},
+	}
+
+	for _, v := range stuffs {
+		initials, err := ParseInitials(v.name, InitialsOptions{Hangul: v.mode, Limit: v.limit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if initials != v.initials {
+			t.Errorf("%s: expected %q got %q", v.name, v.initials, initials)
+		}
+	}
+
+	if _, err := ParseInitials("김민준", InitialsOptions{Hangul: HangulMode(42)}); err != ErrUnsupportedHangulMode {
+		t.Errorf("expected %v got %v", ErrUnsupportedHangulMode, err)
+	}
+}
+
+func TestWordScript(t *testing.T) {
+	stuffs := []struct {
+		word string
+		s    script
+	}{
+		{"孔子", scriptHan},
+		{"山田たろう", scriptHan},
+		{"カタカナ", scriptHan},
+		{"김민준", scriptHangul},
+		{"ㄱㅁㅈ", scriptHangul},
+		{"John", scriptOther},
+		{"김John", scriptOther},
+		{"孔김", scriptOther},
+		{"", scriptOther},
+	}
+
+	for _, v := range stuffs {
+		if got := wordScript(v.word); got != v.s {
+			t.Errorf("%s: expected %v got %v", v.word, v.s, got)
+		}
+	}
+}
+
+func TestInitialsAvatar_DrawHangul(t *testing.T) {
+	fontFile := testFontFile()
+
+	av := New(fontFile)
+	_, err := av.DrawBytes("김민준", DrawOptions{InitialsOptions: InitialsOptions{Hangul: HangulChoseong}})
+	if err != nil {
+		if _, ok := err.(*MissingGlyphError); ok {
+			t.Skip("the font cannot draw Hangul")
+		}
+		t.Fatal(err)
+	}
+}
@@ -1,106 +1,335 @@
 package avatar
 
 import (
-	"bufio"
 	"bytes"
 	"io"
-	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
-const email = "^(((([a-zA-Z]|\\d|[!#\\$%&'\\*\\+\\-\\/=\\?\\^_`{\\|}~]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])+(\\.([a-zA-Z]|\\d|[!#\\$%&'\\*\\+\\-\\/=\\?\\^_`{\\|}~]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])+)*)|((\\x22)((((\\x20|\\x09)*(\\x0d\\x0a))?(\\x20|\\x09)+)?(([\\x01-\\x08\\x0b\\x0c\\x0e-\\x1f\\x7f]|\\x21|[\\x23-\\x5b]|[\\x5d-\\x7e]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])|(\\([\\x01-\\x09\\x0b\\x0c\\x0d-\\x7f]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}]))))*(((\\x20|\\x09)*(\\x0d\\x0a))?(\\x20|\\x09)+)?(\\x22)))@((([a-zA-Z]|\\d|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])|(([a-zA-Z]|\\d|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])([a-zA-Z]|\\d|-|\\.|_|~|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])*([a-zA-Z]|\\d|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])))\\.)+(([a-zA-Z]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])|(([a-zA-Z]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])([a-zA-Z]|\\d|-|\\.|_|~|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])*([a-zA-Z]|[\\x{00A0}-\\x{D7FF}\\x{F900}-\\x{FDCF}\\x{FDF0}-\\x{FFEF}])))\\.?$"
+// InitialsOptions controls how initials are found in a name. The zero value
+// is usable: every field falls back to a default.
+type InitialsOptions struct {
+	// Maximum number of initials (3 by default).
+	Limit int
+
+	// Letter case of the initials (CasingNone by default).
+	Casing Casing
+
+	// Skip email addresses instead of taking the initials of their local
+	// part.
+	SkipEmail bool
+
+	// Initials of Chinese and Japanese names (CJKFirst by default).
+	CJK CJKMode
+
+	// Initials of Korean names (HangulFirst by default).
+	Hangul HangulMode
+
+	// BCP 47 language of the name words, such as "de" or "pt-BR", used
+	// besides English.
+	Locale string
+
+	// Name words used besides the ones of the locale.
+	Words NameWords
+
+	// Keep the honorifics and suffixes of the name words.
+	KeepTitles bool
+
+	// Skip the particles of the name words written in lower case, so that
+	// "Ludwig van Beethoven" gives LB.
+	SkipParticles bool
+
+	// Take one initial for a hyphenated given name, instead of one per part
+	// as in "Jean-Luc Picard" giving JLP.
+	KeepHyphens bool
 
-var regxEmail *regexp.Regexp
+	// Split a name made of a single user name or email address on dots,
+	// underscores, hyphens, digits and camelCase boundaries, so that
+	// "john.doe@example.com" gives JD. Such initials are upper case unless
+	// Casing is set.
+	SplitHandles bool
+
+	// Take numbers as initials, so that "42 Analytics" gives 4A instead of
+	// A.
+	Digits bool
+
+	// Take emoji as initials, so that "🚀 Launch" gives 🚀L instead of L.
+	// InitialsAvatar skips the emoji its fonts have no glyphs for.
+	Emoji bool
+
+	// Symbols and punctuation taken as initials, such as "#&". Others are
+	// skipped at the start of a word, so that "#general" gives g.
+	Symbols string
+
+	// Initials of a name that has none, such as "?". When empty, such names
+	// have no initials and InitialsAvatar returns an *EmptyNameError or an
+	// *UnsupportedCharError.
+	Fallback string
+
+	// Use the options of a draw as they are, without the defaults of
+	// Config.InitialsOptions, so that a draw can turn off a default such as
+	// SplitHandles.
+	IgnoreDefaults bool
+
+	// drawable reports whether the fonts of an InitialsAvatar can draw an
+	// emoji; those they can't are skipped like other symbols. Nil accepts
+	// every emoji.
+	drawable func(string) bool
+}
+
+// setDefaults fills in the zero fields of o from def, unless o ignores
+// defaults, then from the package defaults. The name words of def are added
+// to the ones of o.
+func (o *InitialsOptions) setDefaults(def InitialsOptions) {
+	if o.IgnoreDefaults {
+		def = InitialsOptions{}
+	}
+	if o.Limit == 0 {
+		o.Limit = def.Limit
+	}
+	if o.Casing == CasingDefault {
+		o.Casing = def.Casing
+	}
+	if !o.SkipEmail {
+		o.SkipEmail = def.SkipEmail
+	}
+	if o.CJK == CJKDefault {
+		o.CJK = def.CJK
+	}
+	if o.Hangul == HangulDefault {
+		o.Hangul = def.Hangul
+	}
+	if o.Locale == "" {
+		o.Locale = def.Locale
+	}
+	o.Words = mergeNameWords(o.Words, def.Words)
+	if !o.KeepTitles {
+		o.KeepTitles = def.KeepTitles
+	}
+	if !o.SkipParticles {
+		o.SkipParticles = def.SkipParticles
+	}
+	if !o.KeepHyphens {
+		o.KeepHyphens = def.KeepHyphens
+	}
+	if !o.SplitHandles {
+		o.SplitHandles = def.SplitHandles
+	}
+	if !o.Digits {
+		o.Digits = def.Digits
+	}
+	if !o.Emoji {
+		o.Emoji = def.Emoji
+	}
+	if o.Symbols == "" {
+		o.Symbols = def.Symbols
+	}
+	if o.Fallback == "" {
+		o.Fallback = def.Fallback
+	}
+	if o.Limit == 0 {
+		o.Limit = defaultLimit
+	}
+	if o.Casing == CasingDefault {
+		o.Casing = CasingNone
+	}
+	if o.CJK == CJKDefault {
+		o.CJK = CJKFirst
+	}
+	if o.Hangul == HangulDefault {
+		o.Hangul = HangulFirst
+	}
+}
 
-func init() {
-	regxEmail = regexp.MustCompile(email)
+// validate reports the first invalid field of o.
+func (o *InitialsOptions) validate() error {
+	if o.Limit < 0 {
+		return ErrInvalidLimit
+	}
+	switch o.Casing {
+	case CasingDefault, CasingNone, CasingUpper, CasingLower:
+	default:
+		return ErrUnsupportedCasing
+	}
+	switch o.CJK {
+	case CJKDefault, CJKFirst, CJKGivenName, CJKSurname, CJKSurnameGivenName:
+	default:
+		return ErrUnsupportedCJKMode
+	}
+	switch o.Hangul {
+	case HangulDefault, HangulFirst, HangulChoseong, HangulGivenName:
+	default:
+		return ErrUnsupportedHangulMode
+	}
+	return nil
 }
 
-// This controls how parsing of initials is handled.
-type opts struct {
-	// set all initials to capital letters
-	allCaps bool
+// ParseInitials returns the initials of name as drawn by InitialsAvatar with
+// the same options. Each initial is a grapheme cluster, such as a letter
+// with its combining marks, in Unicode normalization form C.
+//
+// The name may be a mailbox such as `"Doe, John" <john@example.com>`, whose
+// display name is preferred to the address, and may be written "Last,
+// First".
+func ParseInitials(name string, opts InitialsOptions) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+	opts.setDefaults(InitialsOptions{})
 
-	// allow parsing of initials from email messages
-	allowEmail bool
+	name = reorderName(cleanName(name), nameWords(opts.Locale, opts.Words))
+	initials := parseInitials(name, opts)
+	if initials == "" {
+		initials = opts.Fallback
+	}
+	switch opts.Casing {
+	case CasingUpper:
+		initials = strings.ToUpper(initials)
+	case CasingLower:
+		initials = strings.ToLower(initials)
+	}
+	return initials, nil
+}
 
-	// set the maximum number of initials allowed
-	limit int
+// cleanName returns name in normalization form C, without surrounding
+// spaces and bidi formatting characters, which right-to-left input often
+// carries. A mailbox gives its display name, or its address when it has
+// none.
+func cleanName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Bidi_Control, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = norm.NFC.String(strings.TrimSpace(name))
+	if a, ok := parseMailbox(name); ok {
+		name = a.Address
+		if a.Name != "" {
+			name = norm.NFC.String(strings.TrimSpace(a.Name))
+		}
+	}
+	return name
 }
 
-// Tries to find initials in a given src. The src is a name, the logic that is
-// used to decide which characters are used as initials is adopted from the
-// initials project https://github.com/gr2m/initials.
+// Tries to find initials in a given name, the logic that is used to decide
+// which characters are used as initials is adopted from the initials project
+// https://github.com/gr2m/initials.
 //
 // You can pass an opts object to contorl the parsing like setting maximum
 // number of initials and allowing parsing of initials from emails etc.
-func parseInitials(src io.Reader, o opts) (string, error) {
-	scanner := bufio.NewScanner(src)
-	scanner.Split(bufio.ScanWords)
-	var words []string
-	for scanner.Scan() {
-		words = append(words, scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
-		return "", err
+func parseInitials(name string, o InitialsOptions) string {
+	words := strings.Fields(name)
+	if len(words) == 1 {
+		if hw, ok := handleWords(words[0], o); ok {
+			initials := parseInitials(strings.Join(hw, " "), o)
+			if o.Casing == CasingNone {
+				initials = strings.ToUpper(initials)
+			}
+			return initials
+		}
+	}
+	if len(words) > 0 {
+		if _, ok := localPart(words[0]); !ok {
+			words = trimNameWords(words, o)
+		}
+	}
+	if initials, ok := cjkInitials(words, o); ok {
+		return initials
+	}
+	if initials, ok := hangulInitials(words, o); ok {
+		return initials
 	}
 	buf := &bytes.Buffer{}
 	count := 0
 	for i, w := range words {
-		if count >= o.limit {
+		if count >= o.Limit {
 			break
 		}
-		if regxEmail.MatchString(w) {
-			if i == 0 && o.allowEmail {
-				s := strings.Split(w, "@")
-				sr := strings.NewReader(s[0])
-				return parseInitials(sr, o)
+		if local, ok := localPart(w); ok {
+			if i == 0 && !o.SkipEmail {
+				return parseInitials(local, o)
 			}
 			continue
 		}
 		r := strings.NewReader(w)
-		x, _, err := r.ReadRune()
-		if err != nil {
-			return "", err
-		}
+		x, _, _ := r.ReadRune()
 		switch {
-		case unicode.IsLetter(x):
-			if o.allCaps {
-				if unicode.IsLower(x) {
-					x = unicode.ToUpper(x)
-				}
-			}
-			_, _ = buf.WriteRune(x)
-			count++
-		case x == '(':
-			if i > 0 {
-				rb := &bytes.Buffer{}
-			DONE:
-				for {
-					next, _, err := r.ReadRune()
-					switch next {
-					case ')':
-						_, _, err = r.ReadRune()
-						if err != nil {
-							if err != io.EOF {
-								rb.Reset()
-							}
-						}
-						break DONE
-					default:
-						if err != nil {
+		case x == '(' && i > 0:
+			rb := &bytes.Buffer{}
+		DONE:
+			for {
+				next, _, err := r.ReadRune()
+				switch next {
+				case ')':
+					_, _, err = r.ReadRune()
+					if err != nil {
+						if err != io.EOF {
 							rb.Reset()
-							break DONE
 						}
-						_, _ = rb.WriteRune(next)
 					}
+					break DONE
+				default:
+					if err != nil {
+						rb.Reset()
+						break DONE
+					}
+					_, _ = rb.WriteRune(next)
+				}
 
+			}
+			var given strings.Builder
+			for _, g := range graphemes(rb.String()) {
+				if isInitial(g, o) {
+					_, _ = given.WriteString(g)
 				}
-				return rb.String(), nil
+			}
+			if given.Len() == 0 {
+				// an empty, unterminated or rejected paren keeps the initials
+				// before it
+				return buf.String()
+			}
+			return initialsPrefix(given.String(), o.Limit)
+		default:
+			if g := firstInitial(w, o); g != "" {
+				_, _ = buf.WriteString(g)
+				count++
 			}
 		}
 
 	}
-	return buf.String(), nil
+	return buf.String()
+}
+
+// isInitial reports whether the grapheme cluster g is a letter, or a digit,
+// emoji or symbol accepted by o.
+func isInitial(g string, o InitialsOptions) bool {
+	r, _ := utf8.DecodeRuneInString(g)
+	return unicode.IsLetter(r) ||
+		o.Digits && unicode.IsDigit(r) && !isEmoji(g) ||
+		o.Emoji && isEmoji(g) && (o.drawable == nil || o.drawable(g)) ||
+		strings.ContainsRune(o.Symbols, r)
+}
+
+// firstInitial returns the first grapheme cluster of the word w that is a
+// letter, or a digit, emoji or symbol accepted by o. Punctuation and other
+// symbols before it are skipped; anything else, such as a number that is
+// not accepted, makes the word have no initial.
+func firstInitial(w string, o InitialsOptions) string {
+	for w != "" {
+		g := firstGrapheme(w)
+		r, _ := utf8.DecodeRuneInString(g)
+		switch {
+		case isInitial(g, o):
+			return g
+		case !unicode.In(r, unicode.P, unicode.S) && !isEmoji(g):
+			return ""
+		}
+		w = w[len(g):]
+	}
+	return ""
 }
@@ -0,0 +1,235 @@
+package avatar
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NameWords lists words of names that are not drawn as initials. Words are
+// matched regardless of case and dots, so "Dr" also matches "dr." and "PhD"
+// matches "Ph.D.".
+type NameWords struct {
+	// Titles before a name, such as Dr or Mrs.
+	Honorifics []string
+
+	// Generational suffixes, degrees and company forms after a name, such as
+	// Jr, PhD or Inc.
+	Suffixes []string
+
+	// Nobiliary particles, such as van or de, skipped when written in lower
+	// case and InitialsOptions.SkipParticles is set. A particle ending with
+	// an apostrophe or a hyphen, such as d' or al-, is also removed from the
+	// start of a word.
+	Particles []string
+}
+
+// localeWords are the built-in name words by language. The English words
+// are used with every locale, and alone when none is given; their particles
+// are the ones common to names of many languages, such as van, de or bin.
+var localeWords = map[string]NameWords{
+	"en": {
+		Honorifics: []string{"Mr", "Mrs", "Ms", "Miss", "Mx", "Dr", "Prof", "Sir", "Dame", "Lord", "Lady", "Rev", "Fr", "Hon", "Capt", "Col", "Gen", "Lt", "Sgt", "Maj", "Adm"},
+		Suffixes:   []string{"Jr", "Sr", "II", "III", "IV", "V", "PhD", "MD", "DDS", "DVM", "Esq", "MBA", "CPA", "RN", "QC", "KC", "OBE", "MBE", "CBE", "Inc", "Ltd", "LLC", "LLP", "Corp", "Co", "PLC", "GmbH", "AG", "KG", "SA", "SARL", "BV", "NV", "Pty", "SpA", "Srl", "Oy", "AB"},
+		Particles:  []string{"of", "van", "von", "de", "del", "della", "der", "den", "di", "da", "du", "la", "le", "bin", "ibn", "al-", "d'"},
+	},
+	"ar": {
+		Honorifics: []string{"Sheikh", "Shaikh", "Sayyid", "Hajji", "Haji", "الشيخ", "السيد", "الدكتور", "د"},
+		Particles:  []string{"bin", "ibn", "bint", "al-", "el-", "بن", "بنت", "ابن"},
+	},
+	"de": {
+		Honorifics: []string{"Herr", "Frau", "Prof", "Dr", "Dipl-Ing"},
+		Particles:  []string{"von", "vom", "zu", "zum", "zur", "und"},
+	},
+	"es": {
+		Honorifics: []string{"Sr", "Sra", "Srta", "Don", "Doña", "Dña", "Dra", "Lic", "Ing"},
+		Particles:  []string{"de", "del", "la", "las", "los", "y"},
+	},
+	"fr": {
+		Honorifics: []string{"M", "Mme", "Mlle", "Me", "Pr", "Dr"},
+		Particles:  []string{"de", "du", "des", "le", "la", "d'", "l'"},
+	},
+	"it": {
+		Honorifics: []string{"Sig", "Sigra", "Signor", "Signora", "Dott", "Dottssa", "Ing", "Avv"},
+		Particles:  []string{"di", "da", "de", "del", "della", "dei", "degli", "d'", "dell'"},
+	},
+	"nl": {
+		Honorifics: []string{"Dhr", "Mevr", "Mw", "Drs", "Ir", "Mr", "Dr"},
+		Particles:  []string{"van", "der", "den", "de", "het", "ter", "ten", "te", "'t"},
+	},
+	"pt": {
+		Honorifics: []string{"Sr", "Sra", "Dona", "Dom", "Dr", "Dra"},
+		Particles:  []string{"da", "das", "do", "dos", "de", "e"},
+	},
+}
+
+// givenNames are the built-in honorifics and suffixes that are also given
+// names or initials, as in "Gen Hoshino" or "Kumar V". They are removed only
+// when written with a dot or when two more words of the name remain.
+var givenNames = []string{"Lord", "Lady", "Hon", "Col", "Gen", "Maj", "V", "Don", "Dom", "Dona", "M", "Me"}
+
+// nameWords returns the words of English, of the language of the BCP 47
+// locale, such as "de" or "pt-BR", and extra.
+func nameWords(locale string, extra NameWords) NameWords {
+	w := mergeNameWords(extra, localeWords["en"])
+	if lang := language(locale); lang != "en" {
+		w = mergeNameWords(w, localeWords[lang])
+	}
+	return w
+}
+
+// language returns the lower case language subtag of the BCP 47 locale.
+func language(locale string) string {
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	return strings.ToLower(locale)
+}
+
+// mergeNameWords returns the words of a and b in new slices.
+func mergeNameWords(a, b NameWords) NameWords {
+	merge := func(x, y []string) []string {
+		return append(append([]string(nil), x...), y...)
+	}
+	return NameWords{
+		Honorifics: merge(a.Honorifics, b.Honorifics),
+		Suffixes:   merge(a.Suffixes, b.Suffixes),
+		Particles:  merge(a.Particles, b.Particles),
+	}
+}
+
+// foldWord returns w in lower case without dots and trailing commas, for
+// matching against name words.
+func foldWord(w string) string {
+	w = strings.TrimRight(w, ",")
+	return strings.ToLower(strings.Replace(w, ".", "", -1))
+}
+
+// isTitle reports whether w is one of words, to be removed from a name with
+// rest more words. See givenNames.
+func isTitle(words []string, w string, rest int) bool {
+	if !hasWord(words, w) {
+		return false
+	}
+	return rest >= 2 || strings.HasSuffix(strings.TrimRight(w, ","), ".") || !hasWord(givenNames, w)
+}
+
+// hasWord reports whether w is one of words.
+func hasWord(words []string, w string) bool {
+	w = foldWord(w)
+	for _, x := range words {
+		if foldWord(x) == w {
+			return true
+		}
+	}
+	return false
+}
+
+// trimNameWords removes the honorifics and suffixes around the words of a
+// name, the lower case particles when o.SkipParticles is set, and splits
+// hyphenated given names unless o.KeepHyphens is set. A name made of such
+// words only is kept.
+func trimNameWords(words []string, o InitialsOptions) []string {
+	if len(words) == 0 || len(words) == 1 && (o.KeepHyphens || !strings.ContainsAny(words[0], "-‐")) {
+		return words
+	}
+	nw := nameWords(o.Locale, o.Words)
+
+	name := words
+	if !o.KeepTitles {
+		start, end := 0, len(words)
+		for start < end && isTitle(nw.Honorifics, words[start], end-start-1) {
+			start++
+		}
+		for start < end && isTitle(nw.Suffixes, words[end-1], end-start-1) {
+			end--
+		}
+		if start < end {
+			name = words[start:end]
+		}
+	}
+
+	var out []string
+	for i, w := range name {
+		if o.SkipParticles && i > 0 {
+			if isLowerWord(w) && hasWord(nw.Particles, w) {
+				continue
+			}
+			w = trimElidedParticle(w, nw.Particles)
+		}
+		if !o.KeepHyphens && (i < len(name)-1 || len(name) == 1) {
+			out = append(out, strings.FieldsFunc(w, func(r rune) bool { return r == '-' || r == '‐' })...)
+			continue
+		}
+		out = append(out, w)
+	}
+	if len(out) == 0 {
+		return words
+	}
+	return out
+}
+
+// isAcronym reports whether w is made of two or more upper case letters, such
+// as CEO, rather than a given name.
+func isAcronym(w string) bool {
+	n := 0
+	for _, r := range w {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+		n++
+	}
+	return n >= 2
+}
+
+// isLowerWord reports whether w starts with a lower case letter.
+func isLowerWord(w string) bool {
+	r, _ := utf8.DecodeRuneInString(w)
+	return unicode.IsLower(r) || !unicode.IsUpper(r) && !unicode.IsTitle(r)
+}
+
+// trimElidedParticle removes a lower case particle ending with an apostrophe
+// or a hyphen, such as d' in d'Artagnan, from the start of w.
+func trimElidedParticle(w string, particles []string) string {
+	if !isLowerWord(w) {
+		return w
+	}
+	for _, p := range particles {
+		if (strings.HasSuffix(p, "'") || strings.HasSuffix(p, "-")) &&
+			len(w) > len(p) && strings.EqualFold(w[:len(p)], p) {
+			return w[len(p):]
+		}
+	}
+	return w
+}
+
+// reorderName turns a name written "Last, First" into "First Last", keeping
+// the suffixes after the first name at the end. A comma before suffixes only,
+// as in "John Smith, Jr." or "Acme, Inc.", or before an acronym, as in
+// "John Smith, CEO", is kept.
+func reorderName(name string, nw NameWords) string {
+	i := strings.IndexAny(name, ",،")
+	if i < 0 {
+		return name
+	}
+	_, size := utf8.DecodeRuneInString(name[i:])
+	last := strings.TrimSpace(name[:i])
+	first := strings.FieldsFunc(name[i+size:], func(r rune) bool {
+		return r == ',' || r == '،' || unicode.IsSpace(r)
+	})
+
+	given := len(first)
+	for given > 0 && hasWord(nw.Suffixes, first[given-1]) {
+		given--
+	}
+	if last == "" || given == 0 {
+		return name
+	}
+	for _, w := range first[:given] {
+		if isAcronym(w) && !isAcronym(last) {
+			return name
+		}
+	}
+	words := append(append(first[:given:given], last), first[given:]...)
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,71 @@
+package avatar
+
+import (
+	"testing"
+)
+
+func TestParseInitialsNameWords(t *testing.T) {
+	stuffs := []struct {
+		name     string
+		opts     InitialsOptions
+		initials string
+	}{
+		{"Dr. John Smith Jr.", InitialsOptions{}, "JS"},
+		{"Prof. Dr. Jane Doe, PhD", InitialsOptions{}, "JD"},
+		{"Mr John Smith III", InitialsOptions{}, "JS"},
+		{"Dr. John Smith Jr.", InitialsOptions{KeepTitles: true}, "DJS"},
+		{"Sir", InitialsOptions{}, "S"},
+		{"Dr. Jr.", InitialsOptions{}, "DJ"},
+		{"Don Draper", InitialsOptions{}, "DD"},
+		{"Don Draper", InitialsOptions{Locale: "es"}, "DD"},
+		{"Gen Hoshino", InitialsOptions{}, "GH"},
+		{"Hon Lam", InitialsOptions{}, "HL"},
+		{"Lady Gaga", InitialsOptions{}, "LG"},
+		{"Gen. Hoshino", InitialsOptions{}, "H"},
+		{"Gen Mark Milley", InitialsOptions{}, "MM"},
+		{"Kumar V", InitialsOptions{}, "KV"},
+		{"John Smith V", InitialsOptions{}, "JS"},
+		{"Don Diego de la Vega", InitialsOptions{Locale: "es", SkipParticles: true}, "DV"},
+		{"Herr Max Müller", InitialsOptions{Locale: "de-AT"}, "MM"},
+		{"Ludwig van Beethoven", InitialsOptions{}, "LvB"},
+		{"Ludwig van Beethoven", InitialsOptions{Locale: "nl", SkipParticles: true}, "LB"},
+		{"Ludwig van Beethoven", InitialsOptions{Locale: "nl_BE", SkipParticles: true}, "LB"},
+		{"Ludwig van Beethoven", InitialsOptions{SkipParticles: true}, "LB"},
+		{"Vincent van Gogh", InitialsOptions{SkipParticles: true, Limit: 2}, "VG"},
+		{"Charles de Gaulle", InitialsOptions{SkipParticles: true}, "CG"},
+		{"Omar al-Farooq", InitialsOptions{SkipParticles: true}, "OF"},
+		{"Vincent Van Gogh", InitialsOptions{Locale: "nl", SkipParticles: true}, "VVG"},
+		{"Johann Wolfgang von Goethe", InitialsOptions{Locale: "de", SkipParticles: true, Limit: 4}, "JWG"},
+		{"Charles d'Artagnan", InitialsOptions{Locale: "fr", SkipParticles: true}, "CA"},
+		{"Mohammed bin Salman", InitialsOptions{Locale: "ar", SkipParticles: true}, "MS"},
+		{"Omar al-Farooq", InitialsOptions{Locale: "ar", SkipParticles: true}, "OF"},
+		{"Jean-Luc Picard", InitialsOptions{}, "JLP"},
+		{"Jean-Luc Picard", InitialsOptions{KeepHyphens: true}, "JP"},
+		{"Mary-Jane", InitialsOptions{}, "MJ"},
+		{"Anne Smith-Jones", InitialsOptions{}, "AS"},
+		{"Senor Pedro Garcia", InitialsOptions{Words: NameWords{Honorifics: []string{"Senor"}}}, "PG"},
+		{"John Smith CFA", InitialsOptions{Words: NameWords{Suffixes: []string{"C.F.A."}}}, "JS"},
+	}
+
+	for _, v := range stuffs {
+		initials, err := ParseInitials(v.name, v.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if initials != v.initials {
+			t.Errorf("%s: expected %q got %q", v.name, v.initials, initials)
+		}
+	}
+}
+
+func TestInitialsOptions_setDefaultsWords(t *testing.T) {
+	def := InitialsOptions{Locale: "nl", SkipParticles: true, Words: NameWords{Honorifics: []string{"Ir"}}}
+	o := InitialsOptions{Words: NameWords{Honorifics: []string{"Ing"}}}
+	o.setDefaults(def)
+	if o.Locale != "nl" || !o.SkipParticles || len(o.Words.Honorifics) != 2 {
+		t.Errorf("expected the instance words and options added got %+v", o)
+	}
+	if len(def.Words.Honorifics) != 1 {
+		t.Errorf("expected the instance words unchanged got %v", def.Words.Honorifics)
+	}
+}
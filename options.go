@@ -0,0 +1,233 @@
+package avatar
+
+import (
+	"errors"
+	"image/color"
+)
+
+const (
+	// FormatPNG encodes avatars as png images.
+	FormatPNG = "png"
+
+	// FormatJPEG encodes avatars as jpeg images.
+	FormatJPEG = "jpeg"
+
+	// FormatSVG encodes avatars as svg documents, resolution independent
+	// images with the glyphs drawn as paths.
+	FormatSVG = "svg"
+)
+
+const (
+	defaultSize  = 48
+	defaultLimit = 3
+
+	// maxSize bounds the side length of an image, so a size taken from a
+	// request can't make the drawer allocate gigabytes. It bounds the font
+	// size too, as larger glyphs overflow the rasterizer.
+	maxSize = 4096
+
+	// maxFontRatio bounds Config.FontRatio and Config.FontScales.
+	maxFontRatio = 4
+)
+
+// Shape is the outline of the avatar background.
+type Shape int
+
+const (
+	// ShapeSquare fills the whole image.
+	ShapeSquare Shape = iota
+
+	// ShapeCircle is the circle inscribed in the image.
+	ShapeCircle
+
+	// ShapeRoundedRect is the image with corners rounded by DrawOptions.Radius.
+	ShapeRoundedRect
+
+	// ShapeSquircle is the superellipse |x|⁴ + |y|⁴ = 1 inscribed in the
+	// image, a square with continuously curved sides.
+	ShapeSquircle
+)
+
+// Centering selects how the initials are centered in the image.
+type Centering int
+
+const (
+	// CenterBounds centers the bounding box of the drawn glyphs.
+	CenterBounds Centering = iota
+
+	// CenterBaseline centers the advance width horizontally and the band
+	// between the baseline and the cap height vertically, so that initials
+	// with and without descenders or accents share a baseline.
+	CenterBaseline
+)
+
+// Casing controls the letter case of the drawn initials.
+type Casing int
+
+const (
+	// CasingDefault uses the casing of Config.InitialsOptions, or CasingNone.
+	CasingDefault Casing = iota
+
+	// CasingNone keeps the initials as they appear in the name.
+	CasingNone
+
+	// CasingUpper draws the initials in upper case.
+	CasingUpper
+
+	// CasingLower draws the initials in lower case.
+	CasingLower
+)
+
+var (
+	// ErrInvalidSize matches every *InvalidSizeError with errors.Is.
+	ErrInvalidSize = errors.New("avatar: invalid size")
+
+	// ErrInvalidFontSize is returned when the font size is negative, NaN or
+	// above 4096, or the font ratio or a font scale is out of range.
+	ErrInvalidFontSize = errors.New("avatar: invalid font size")
+
+	// ErrInvalidLimit is returned when the initials limit is negative.
+	ErrInvalidLimit = errors.New("avatar: invalid initials limit")
+
+	// ErrInvalidRadius is returned when the corner radius is negative or
+	// larger than half the image size.
+	ErrInvalidRadius = errors.New("avatar: invalid radius")
+
+	// ErrInvalidPadding is returned when the padding leaves no room for the initials.
+	ErrInvalidPadding = errors.New("avatar: invalid padding")
+
+	// ErrUnsupportedShape is returned when the given shape is not supported.
+	ErrUnsupportedShape = errors.New("avatar: unsupported shape")
+
+	// ErrUnsupportedCentering is returned when the given centering is not supported.
+	ErrUnsupportedCentering = errors.New("avatar: unsupported centering")
+
+	// ErrUnsupportedCasing is returned when the given casing is not supported.
+	ErrUnsupportedCasing = errors.New("avatar: unsupported casing")
+
+	// ErrUnsupportedCJKMode is returned when the given CJK mode is not supported.
+	ErrUnsupportedCJKMode = errors.New("avatar: unsupported CJK mode")
+
+	// ErrUnsupportedHangulMode is returned when the given Hangul mode is not supported.
+	ErrUnsupportedHangulMode = errors.New("avatar: unsupported Hangul mode")
+)
+
+// DrawOptions controls how a single avatar is drawn. The zero value is
+// usable: every field falls back to a default.
+type DrawOptions struct {
+	// Side length of the square image in pixels (48 by default, 4096 at
+	// most).
+	Size int
+
+	// Image encoding used by DrawBytes, FormatPNG (default), FormatJPEG or
+	// FormatSVG.
+	Format string
+
+	// Background color. By default a color is picked from the hash of
+	// ColorKey, or of the name when ColorKey is empty.
+	Background color.Color
+
+	// Stable key the background color is picked from, such as a user ID or
+	// an email address, so that the color does not change with the name and
+	// differs between people of the same name.
+	ColorKey string
+
+	// Color of the initials (white by default).
+	Foreground color.Color
+
+	// Outline of the background (ShapeSquare by default). The image outside
+	// the shape is transparent.
+	Shape Shape
+
+	// Corner radius in pixels of ShapeRoundedRect (an eighth of Size by
+	// default).
+	Radius int
+
+	// Color outside the shape for formats without transparency, such as
+	// FormatJPEG (white by default).
+	Matte color.Color
+
+	// TrueType font size, 4096 at most. Zero uses Config.FontSize, or a size
+	// computed from the image size when that is zero too.
+	FontSize float64
+
+	// How the initials are found in the name, shared with ParseInitials.
+	InitialsOptions
+
+	// How the initials are centered (CenterBounds by default).
+	Centering Centering
+
+	// Space in pixels between the image edges and the box the initials are
+	// centered in.
+	Padding int
+}
+
+// setDefaults fills in the zero fields of o, the initials options from
+// initials first. The background depends on the name and is resolved by the
+// caller.
+func (o *DrawOptions) setDefaults(fontSize float64, initials InitialsOptions) {
+	if o.Size == 0 {
+		o.Size = defaultSize
+	}
+	if o.Format == "" {
+		o.Format = FormatPNG
+	}
+	if o.Foreground == nil {
+		o.Foreground = color.White
+	}
+	if o.Shape == ShapeRoundedRect && o.Radius == 0 {
+		o.Radius = o.Size / 8
+	}
+	if o.Matte == nil {
+		o.Matte = color.White
+	}
+	if o.FontSize == 0 {
+		o.FontSize = fontSize
+	}
+	o.InitialsOptions.setDefaults(initials)
+}
+
+// validate reports the first invalid field of o.
+func (o *DrawOptions) validate() error {
+	if o.Size < 0 || o.Size > maxSize {
+		return &InvalidSizeError{Size: o.Size}
+	}
+	switch o.Format {
+	case "", FormatPNG, FormatJPEG, FormatSVG:
+	default:
+		return &UnsupportedFormatError{Format: o.Format}
+	}
+	switch o.Shape {
+	case ShapeSquare, ShapeCircle, ShapeRoundedRect, ShapeSquircle:
+	default:
+		return ErrUnsupportedShape
+	}
+	if !validFontSize(o.FontSize, maxSize) {
+		return ErrInvalidFontSize
+	}
+	if err := o.InitialsOptions.validate(); err != nil {
+		return err
+	}
+	switch o.Centering {
+	case CenterBounds, CenterBaseline:
+	default:
+		return ErrUnsupportedCentering
+	}
+	size := o.Size
+	if size == 0 {
+		size = defaultSize
+	}
+	if o.Radius < 0 || o.Radius > size-o.Radius {
+		return ErrInvalidRadius
+	}
+	if o.Padding < 0 || o.Padding >= size-o.Padding {
+		return ErrInvalidPadding
+	}
+	return nil
+}
+
+// validFontSize reports whether f is a font size, ratio or scale between zero
+// and max.
+func validFontSize(f, max float64) bool {
+	return f >= 0 && f <= max
+}
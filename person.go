@@ -0,0 +1,116 @@
+package avatar
+
+import (
+	"image"
+	"strings"
+)
+
+// Person is a name split in parts, such as a user directory record. Its
+// initials come from the given and family names, or from the nickname when
+// both are empty, or from the email address when the nickname is empty too.
+// Its color comes from the ID, or from the email address when there is no
+// ID, unless DrawOptions.ColorKey is set, so that it does not change when
+// the person is renamed.
+type Person struct {
+	// Given name, such as John, or 小明.
+	GivenName string
+
+	// Family name, such as Doe, or 王.
+	FamilyName string
+
+	// Name used when the given and family names are unknown.
+	Nickname string
+
+	// Email address, or mailbox with a display name.
+	Email string
+
+	// Stable identifier, such as a user ID or a UUID.
+	ID string
+
+	// BCP 47 language of the name, such as "de" or "zh-TW", used when the
+	// initials options have no locale. Family names come first in Chinese,
+	// Hungarian, Japanese, Korean and Vietnamese.
+	Locale string
+}
+
+// familyFirst lists the languages that write the family name before the
+// given name.
+var familyFirst = map[string]bool{
+	"hu": true,
+	"ja": true,
+	"ko": true,
+	"vi": true,
+	"zh": true,
+}
+
+// name returns the name the initials of p are taken from.
+func (p *Person) name() string {
+	given, family := strings.TrimSpace(p.GivenName), strings.TrimSpace(p.FamilyName)
+	switch {
+	case given == "" && family == "":
+		if nick := strings.TrimSpace(p.Nickname); nick != "" {
+			return nick
+		}
+		return p.Email
+	case given == "":
+		return family
+	case family == "":
+		return given
+	case familyFirst[language(p.Locale)] || wordScript(family) != scriptOther:
+		return family + " " + given
+	}
+	return given + " " + family
+}
+
+// colorKey returns the key the color of p is derived from, or "" to derive
+// it from the name.
+func (p *Person) colorKey() string {
+	if id := strings.TrimSpace(p.ID); id != "" {
+		return id
+	}
+	email := strings.TrimSpace(p.Email)
+	if a, ok := parseMailbox(email); ok {
+		email = a.Address
+	}
+	return strings.ToLower(email)
+}
+
+// Initials returns the initials of p as drawn by InitialsAvatar with the
+// same options.
+func (p Person) Initials(opts InitialsOptions) (string, error) {
+	if opts.Locale == "" {
+		opts.Locale = p.Locale
+	}
+	return ParseInitials(p.name(), opts)
+}
+
+// defaultPerson fills in the locale and color key of o from p.
+func (o *DrawOptions) defaultPerson(p *Person) {
+	if o.Locale == "" {
+		o.Locale = p.Locale
+	}
+	if o.ColorKey == "" {
+		o.ColorKey = p.colorKey()
+	}
+}
+
+// DrawPerson draws an image of the initials of p styled by opts.
+func (a *InitialsAvatar) DrawPerson(p Person, opts DrawOptions) (image.Image, error) {
+	opts.defaultPerson(&p)
+	initials, err := a.prepare(p.name(), &opts)
+	if err != nil {
+		return nil, err
+	}
+	return a.draw(initials, &opts)
+}
+
+// DrawPersonBytes draws an image like DrawPerson and encodes it in
+// opts.Format.
+func (a *InitialsAvatar) DrawPersonBytes(p Person, opts DrawOptions) ([]byte, error) {
+	opts.defaultPerson(&p)
+	initials, err := a.prepare(p.name(), &opts)
+	if err != nil {
+		return nil, err
+	}
+	return a.drawBytes(initials, &opts)
+}
@@ -0,0 +1,103 @@
+package avatar
+
+import (
+	"bytes"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestPerson_Initials(t *testing.T) {
+	stuffs := []struct {
+		person   Person
+		opts     InitialsOptions
+		initials string
+	}{
+		{Person{GivenName: "John", FamilyName: "Doe"}, InitialsOptions{}, "JD"},
+		{Person{GivenName: "Mary Ann", FamilyName: "Smith"}, InitialsOptions{}, "MAS"},
+		{Person{GivenName: "John"}, InitialsOptions{}, "J"},
+		{Person{FamilyName: "Doe"}, InitialsOptions{}, "D"},
+		{Person{GivenName: "János", FamilyName: "Nagy", Locale: "hu"}, InitialsOptions{}, "NJ"},
+		{Person{GivenName: "Taro", FamilyName: "Yamada", Locale: "ja-JP"}, InitialsOptions{}, "YT"},
+		{Person{GivenName: "小明", FamilyName: "王"}, InitialsOptions{CJK: CJKGivenName}, "小明"},
+		{Person{GivenName: "阳明", FamilyName: "欧"}, InitialsOptions{CJK: CJKSurname}, "欧"},
+		{Person{GivenName: "민준", FamilyName: "김"}, InitialsOptions{Hangul: HangulGivenName}, "민준"},
+		{Person{GivenName: "Ludwig", FamilyName: "van Beethoven", Locale: "nl"}, InitialsOptions{SkipParticles: true}, "LB"},
+		{Person{Nickname: "jdoe", Email: "john@example.com"}, InitialsOptions{}, "j"},
+		{Person{Nickname: "  ", Email: "john.doe@example.com"}, InitialsOptions{SplitHandles: true}, "JD"},
+		{Person{Email: `"Doe, John" <john@example.com>`}, InitialsOptions{}, "JD"},
+		{Person{ID: "42"}, InitialsOptions{}, ""},
+	}
+
+	for _, v := range stuffs {
+		initials, err := v.person.Initials(v.opts)
+		if err != nil {
+			t.Errorf("%+v: unexpected error %v", v.person, err)
+		}
+		if initials != v.initials {
+			t.Errorf("%+v: expected %q got %q", v.person, v.initials, initials)
+		}
+	}
+}
+
+func TestPerson_colorKey(t *testing.T) {
+	stuffs := []struct {
+		person Person
+		key    string
+	}{
+		{Person{ID: " 42 ", Email: "john@example.com"}, "42"},
+		{Person{Email: "John@Example.com"}, "john@example.com"},
+		{Person{Email: "John Doe <John@Example.com>"}, "john@example.com"},
+		{Person{GivenName: "John"}, ""},
+	}
+
+	for _, v := range stuffs {
+		if key := v.person.colorKey(); key != v.key {
+			t.Errorf("%+v: expected %q got %q", v.person, v.key, key)
+		}
+	}
+}
+
+func TestInitialsAvatar_DrawPerson(t *testing.T) {
+	av := New(testFontFile())
+
+	// the color follows the ID across renames
+	background := func(p Person) color.Color {
+		m, err := av.DrawPerson(p, DrawOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return color.RGBAModel.Convert(m.At(0, 0))
+	}
+	jon := background(Person{GivenName: "Jon", ID: "u1"})
+	if c := background(Person{GivenName: "Jonathan", ID: "u1"}); c != jon {
+		t.Errorf("expected %v got %v", jon, c)
+	}
+	if c, want := background(Person{GivenName: "Jon"}), color.RGBAModel.Convert(getColorByName("Jon")); c != want {
+		t.Errorf("expected %v got %v", want, c)
+	}
+	m, err := av.DrawPerson(Person{GivenName: "Jon", ID: "u1"}, DrawOptions{ColorKey: "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, want := color.RGBAModel.Convert(m.At(0, 0)), color.RGBAModel.Convert(av.Color("k")); c != want {
+		t.Errorf("expected %v got %v", want, c)
+	}
+
+	p := Person{GivenName: "John", FamilyName: "Doe", ID: "u2"}
+	b1, err := av.DrawPersonBytes(p, DrawOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := av.DrawBytes("John Doe", DrawOptions{Background: getColorByName("u2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Error("expected the person drawn like their name")
+	}
+
+	if _, err := av.DrawPersonBytes(Person{ID: "u3"}, DrawOptions{}); !reflect.DeepEqual(err, &EmptyNameError{}) {
+		t.Errorf("expected %v got %v", &EmptyNameError{}, err)
+	}
+}
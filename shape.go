@@ -0,0 +1,114 @@
+package avatar
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/math/f32"
+	"golang.org/x/image/vector"
+)
+
+// kappa is the distance of the control points of a cubic Bézier curve that
+// approximates a quarter circle of radius 1.
+const kappa = 0.5522847498
+
+// squircleExponent is the exponent of the superellipse |x|^n + |y|^n = 1
+// drawn for ShapeSquircle.
+const squircleExponent = 4
+
+// segment is a part of a closed path. Op is 'M' (move to P[0]), 'L' (line
+// to P[0]) or 'C' (cubic curve to P[2] with control points P[0] and P[1]).
+type segment struct {
+	op byte
+	p  [3]f32.Vec2
+}
+
+// shapePath returns the outline of the shape filling a square of the given
+// side length, or nil for ShapeSquare which needs no clipping.
+func shapePath(shape Shape, size, radius float32) []segment {
+	switch shape {
+	case ShapeCircle:
+		return roundedRectPath(size, size/2)
+	case ShapeRoundedRect:
+		return roundedRectPath(size, radius)
+	case ShapeSquircle:
+		return squirclePath(size)
+	}
+	return nil
+}
+
+// roundedRectPath returns a square with corners rounded by quarter circles of
+// radius r. A radius of half the size makes a circle.
+func roundedRectPath(size, r float32) []segment {
+	k := r * (1 - kappa)
+	s := size
+	return []segment{
+		{op: 'M', p: [3]f32.Vec2{{r, 0}}},
+		{op: 'L', p: [3]f32.Vec2{{s - r, 0}}},
+		{op: 'C', p: [3]f32.Vec2{{s - k, 0}, {s, k}, {s, r}}},
+		{op: 'L', p: [3]f32.Vec2{{s, s - r}}},
+		{op: 'C', p: [3]f32.Vec2{{s, s - k}, {s - k, s}, {s - r, s}}},
+		{op: 'L', p: [3]f32.Vec2{{r, s}}},
+		{op: 'C', p: [3]f32.Vec2{{k, s}, {0, s - k}, {0, s - r}}},
+		{op: 'L', p: [3]f32.Vec2{{0, r}}},
+		{op: 'C', p: [3]f32.Vec2{{0, k}, {k, 0}, {r, 0}}},
+	}
+}
+
+// squirclePath returns a superellipse inscribed in the square as a polygon.
+func squirclePath(size float32) []segment {
+	const n = 256 // vertices
+	c := float64(size) / 2
+	path := make([]segment, 0, n)
+	for i := 0; i < n; i++ {
+		t := 2 * math.Pi * float64(i) / n
+		cos, sin := math.Cos(t), math.Sin(t)
+		x := c + c*math.Copysign(math.Pow(math.Abs(cos), 2.0/squircleExponent), cos)
+		y := c + c*math.Copysign(math.Pow(math.Abs(sin), 2.0/squircleExponent), sin)
+		op := byte('L')
+		if i == 0 {
+			op = 'M'
+		}
+		path = append(path, segment{op: op, p: [3]f32.Vec2{{float32(x), float32(y)}}})
+	}
+	return path
+}
+
+// shapeMask rasterizes path into an anti-aliased alpha mask of the given
+// side length.
+func shapeMask(path []segment, size int) *image.Alpha {
+	z := vector.NewRasterizer(size, size)
+	z.DrawOp = draw.Src
+	for _, s := range path {
+		switch s.op {
+		case 'M':
+			z.MoveTo(s.p[0])
+		case 'L':
+			z.LineTo(s.p[0])
+		case 'C':
+			z.CubeTo(s.p[0], s.p[1], s.p[2])
+		}
+	}
+	z.ClosePath()
+
+	mask := image.NewAlpha(image.Rect(0, 0, size, size))
+	z.Draw(mask, mask.Bounds(), image.Opaque, image.ZP)
+	return mask
+}
+
+// clip cuts m to o.Shape. The area outside the shape is transparent, or
+// o.Matte for formats without transparency.
+func clip(m image.Image, o *DrawOptions) image.Image {
+	path := shapePath(o.Shape, float32(o.Size), float32(o.Radius))
+	if path == nil {
+		return m
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, o.Size, o.Size))
+	if o.Format == FormatJPEG {
+		draw.Draw(dst, dst.Bounds(), &image.Uniform{o.Matte}, image.ZP, draw.Src)
+	}
+	draw.DrawMask(dst, dst.Bounds(), m, image.ZP, shapeMask(path, o.Size), image.ZP, draw.Over)
+	return dst
+}
@@ -0,0 +1,101 @@
+package avatar
+
+import (
+	"bytes"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestInitialsAvatar_DrawShapes(t *testing.T) {
+	av := New("")
+	size := 64
+
+	for _, shape := range []Shape{ShapeCircle, ShapeRoundedRect, ShapeSquircle} {
+		m, err := av.Draw("John Doe", DrawOptions{Size: size, Shape: shape})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, a := m.At(0, 0).RGBA(); a != 0 {
+			t.Errorf("shape %d: expected a transparent corner got alpha %d", shape, a)
+		}
+		if _, _, _, a := m.At(size/2, 1).RGBA(); a != 0xffff {
+			t.Errorf("shape %d: expected an opaque edge middle got alpha %d", shape, a)
+		}
+
+		// the edges are anti-aliased
+		partial := false
+		for x := 0; x < size/2; x++ {
+			if _, _, _, a := m.At(x, x).RGBA(); a > 0 && a < 0xffff {
+				partial = true
+			}
+		}
+		if !partial {
+			t.Errorf("shape %d: edges are not anti-aliased", shape)
+		}
+	}
+
+	// a square has no transparent area
+	m, _ := av.Draw("John Doe", DrawOptions{Size: size})
+	if _, _, _, a := m.At(0, 0).RGBA(); a != 0xffff {
+		t.Errorf("square: expected an opaque corner got alpha %d", a)
+	}
+}
+
+func TestInitialsAvatar_DrawShapeRadius(t *testing.T) {
+	av := New("")
+
+	// the larger the radius the more of the corner is cut
+	small, _ := av.Draw("John Doe", DrawOptions{Size: 64, Shape: ShapeRoundedRect, Radius: 4})
+	large, _ := av.Draw("John Doe", DrawOptions{Size: 64, Shape: ShapeRoundedRect, Radius: 24})
+	if _, _, _, a := small.At(4, 4).RGBA(); a != 0xffff {
+		t.Errorf("radius 4: expected an opaque pixel got alpha %d", a)
+	}
+	if _, _, _, a := large.At(4, 4).RGBA(); a != 0 {
+		t.Errorf("radius 24: expected a transparent pixel got alpha %d", a)
+	}
+
+	if _, err := av.Draw("John Doe", DrawOptions{Size: 64, Shape: ShapeRoundedRect, Radius: 33}); err != ErrInvalidRadius {
+		t.Errorf("expected %v got %v", ErrInvalidRadius, err)
+	}
+}
+
+func TestInitialsAvatar_DrawShapeMatte(t *testing.T) {
+	av := New("")
+	matte := color.RGBA{255, 0, 0, 255}
+
+	raw, err := av.DrawBytes("John Doe", DrawOptions{Size: 64, Shape: ShapeCircle, Format: FormatJPEG, Matte: matte})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := jpeg.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, g, b, _ := m.At(1, 1).RGBA()
+	if r>>8 < 240 || g>>8 > 15 || b>>8 > 15 {
+		t.Errorf("expected the matte color at the corner got %v", m.At(1, 1))
+	}
+
+	// the matte does not apply to formats with transparency
+	png, _ := av.Draw("John Doe", DrawOptions{Size: 64, Shape: ShapeCircle, Matte: matte})
+	if got := color.RGBAModel.Convert(png.At(1, 1)); got != (color.RGBA{}) {
+		t.Errorf("expected a transparent corner got %v", got)
+	}
+}
+
+func TestShapeMask(t *testing.T) {
+	mask := shapeMask(shapePath(ShapeCircle, 100, 0), 100)
+	var area float64
+	for _, a := range mask.Pix {
+		area += float64(a) / 255
+	}
+	// π·50² ≈ 7854
+	if area < 7800 || area > 7900 {
+		t.Errorf("circle area %.0f, expected about 7854", area)
+	}
+
+	if shapePath(ShapeSquare, 100, 0) != nil {
+		t.Error("a square needs no path")
+	}
+}
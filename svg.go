@@ -0,0 +1,186 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// DrawSVG draws s as an SVG document. The glyphs are converted to paths, so
+// the document does not depend on the fonts installed where it is viewed.
+func (g *drawer) DrawSVG(s string, o *DrawOptions) ([]byte, error) {
+	runs, err := g.runs(s, o.FontSize)
+	if err != nil {
+		return nil, err
+	}
+	defer g.release(runs)
+
+	buf := new(bytes.Buffer)
+	writeSVGStart(buf, o)
+	writeSVGShape(buf, o)
+
+	// draw the text
+	var d bytes.Buffer
+	dot, err := g.origin(runs, o.Size, o.Centering)
+	if err != nil {
+		return nil, err
+	}
+	scale := fixed.Int26_6(0.5 + (o.FontSize * g.dpi * 64 / 72))
+	var gbuf truetype.GlyphBuf
+	for _, run := range runs {
+		ttf := g.fonts[run.key.font].ttf
+		prevC := rune(-1)
+		for _, c := range run.s {
+			if prevC >= 0 {
+				dot.X += run.face.Kern(prevC, c)
+			}
+			if err := gbuf.Load(ttf, scale, ttf.Index(c), font.HintingNone); err != nil {
+				return nil, &FontError{Err: err}
+			}
+			start := 0
+			for _, end := range gbuf.Ends {
+				writeContour(&d, gbuf.Points[start:end], dot)
+				start = end
+			}
+			dot.X += gbuf.AdvanceWidth
+			prevC = c
+		}
+	}
+	if d.Len() > 0 {
+		fmt.Fprintf(buf, `<path d="%s"%s/>`, d.Bytes(), svgFill(o.Foreground))
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
+
+// imageSVG wraps m, an image drawn for o, in an SVG document.
+func imageSVG(m image.Image, o *DrawOptions) ([]byte, error) {
+	var p bytes.Buffer
+	if err := png.Encode(&p, m); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	writeSVGStart(buf, o)
+	fmt.Fprintf(buf, `<image width="%d" height="%d" href="data:image/png;base64,%s"/>`,
+		o.Size, o.Size, base64.StdEncoding.EncodeToString(p.Bytes()))
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
+
+func writeSVGStart(buf *bytes.Buffer, o *DrawOptions) {
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		o.Size, o.Size, o.Size, o.Size)
+}
+
+// writeSVGShape writes the background of o.Shape as an SVG primitive.
+func writeSVGShape(buf *bytes.Buffer, o *DrawOptions) {
+	fill := svgFill(o.Background)
+	switch o.Shape {
+	case ShapeCircle:
+		r := svgNum(float64(o.Size) / 2)
+		fmt.Fprintf(buf, `<circle cx="%s" cy="%s" r="%s"%s/>`, r, r, r, fill)
+	case ShapeRoundedRect:
+		fmt.Fprintf(buf, `<rect width="%d" height="%d" rx="%d"%s/>`, o.Size, o.Size, o.Radius, fill)
+	case ShapeSquircle:
+		var d bytes.Buffer
+		for _, s := range shapePath(o.Shape, float32(o.Size), float32(o.Radius)) {
+			d.WriteByte(s.op)
+			n := 1
+			if s.op == 'C' {
+				n = 3
+			}
+			for _, p := range s.p[:n] {
+				fmt.Fprintf(&d, "%s %s ", svgNum(float64(p[0])), svgNum(float64(p[1])))
+			}
+		}
+		d.WriteByte('Z')
+		fmt.Fprintf(buf, `<path d="%s"%s/>`, d.Bytes(), fill)
+	default:
+		fmt.Fprintf(buf, `<rect width="%d" height="%d"%s/>`, o.Size, o.Size, fill)
+	}
+}
+
+// writeContour writes the closed contour ps of a glyph drawn at dot as SVG
+// path data.
+//
+// The low bit of each point's Flags value is whether the point is on the
+// curve. TrueType fonts only have quadratic Bézier curves, thus two
+// consecutive off-curve points imply an on-curve point in the middle of
+// those two. See http://chanae.walon.org/pub/ttf/ttf_glyphs.htm for more
+// details.
+func writeContour(d *bytes.Buffer, ps []truetype.Point, dot fixed.Point26_6) {
+	if len(ps) == 0 {
+		return
+	}
+
+	// glyph points have positive Y going upwards, SVG downwards.
+	pt := func(p truetype.Point) fixed.Point26_6 {
+		return fixed.Point26_6{X: dot.X + p.X, Y: dot.Y - p.Y}
+	}
+	mid := func(a, b fixed.Point26_6) fixed.Point26_6 {
+		return fixed.Point26_6{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+	}
+	on := func(p truetype.Point) bool { return p.Flags&0x01 != 0 }
+
+	start := pt(ps[0])
+	others := ps[1:]
+	if !on(ps[0]) {
+		last := pt(ps[len(ps)-1])
+		if on(ps[len(ps)-1]) {
+			start = last
+			others = ps[:len(ps)-1]
+		} else {
+			start = mid(start, last)
+			others = ps
+		}
+	}
+
+	fmt.Fprintf(d, "M%s ", svgPoint(start))
+	q0, on0 := start, true
+	for _, p := range others {
+		q := pt(p)
+		switch {
+		case on(p) && on0:
+			fmt.Fprintf(d, "L%s ", svgPoint(q))
+		case on(p):
+			fmt.Fprintf(d, "Q%s %s ", svgPoint(q0), svgPoint(q))
+		case !on0:
+			fmt.Fprintf(d, "Q%s %s ", svgPoint(q0), svgPoint(mid(q0, q)))
+		}
+		q0, on0 = q, on(p)
+	}
+	if !on0 {
+		fmt.Fprintf(d, "Q%s %s ", svgPoint(q0), svgPoint(start))
+	}
+	d.WriteByte('Z')
+}
+
+func svgPoint(p fixed.Point26_6) string {
+	return svgNum(float64(p.X)/64) + " " + svgNum(float64(p.Y)/64)
+}
+
+// svgNum formats v with at most two decimals.
+func svgNum(v float64) string {
+	return strconv.FormatFloat(math.Floor(v*100+0.5)/100, 'f', -1, 64)
+}
+
+// svgFill returns the fill attributes of c.
+func svgFill(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	fill := fmt.Sprintf(` fill="#%02x%02x%02x"`, n.R, n.G, n.B)
+	if n.A != 0xff {
+		fill += ` fill-opacity="` + svgNum(float64(n.A)/0xff) + `"`
+	}
+	return fill
+}
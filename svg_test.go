@@ -0,0 +1,152 @@
+package avatar
+
+import (
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/math/f32"
+	"golang.org/x/image/vector"
+)
+
+type svgDoc struct {
+	Width   int    `xml:"width,attr"`
+	ViewBox string `xml:"viewBox,attr"`
+	Rects   []struct {
+		Fill string `xml:"fill,attr"`
+		RX   string `xml:"rx,attr"`
+	} `xml:"rect"`
+	Circles []struct {
+		R string `xml:"r,attr"`
+	} `xml:"circle"`
+	Paths []struct {
+		D    string `xml:"d,attr"`
+		Fill string `xml:"fill,attr"`
+	} `xml:"path"`
+}
+
+// rasterizePath draws SVG path data made of M, L, Q and Z commands.
+func rasterizePath(t *testing.T, d string, size int) *image.Alpha {
+	z := vector.NewRasterizer(size, size)
+	fields := strings.Fields(strings.NewReplacer("M", " M ", "L", " L ", "Q", " Q ", "Z", " Z ").Replace(d))
+	num := func(i int) float32 {
+		v, err := strconv.ParseFloat(fields[i], 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return float32(v)
+	}
+	for i := 0; i < len(fields); {
+		switch fields[i] {
+		case "M":
+			z.MoveTo(f32.Vec2{num(i + 1), num(i + 2)})
+			i += 3
+		case "L":
+			z.LineTo(f32.Vec2{num(i + 1), num(i + 2)})
+			i += 3
+		case "Q":
+			z.QuadTo(f32.Vec2{num(i + 1), num(i + 2)}, f32.Vec2{num(i + 3), num(i + 4)})
+			i += 5
+		case "Z":
+			z.ClosePath()
+			i++
+		default:
+			t.Fatalf("unexpected path command %q", fields[i])
+		}
+	}
+	m := image.NewAlpha(image.Rect(0, 0, size, size))
+	z.DrawOp = draw.Src
+	z.Draw(m, m.Bounds(), image.Opaque, image.ZP)
+	return m
+}
+
+func TestInitialsAvatar_DrawBytesSVG(t *testing.T) {
+	av := New("")
+	size := 128
+
+	for _, name := range []string{"John Doe", "Wendy gray"} {
+		raw, err := av.DrawBytes(name, DrawOptions{Size: size, Format: FormatSVG, Background: color.RGBA{69, 189, 243, 255}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc svgDoc
+		if err := xml.Unmarshal(raw, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.Width != size || doc.ViewBox != "0 0 128 128" {
+			t.Errorf("%s: unexpected size %d, view box %q", name, doc.Width, doc.ViewBox)
+		}
+		if len(doc.Rects) != 1 || doc.Rects[0].Fill != "#45bdf3" {
+			t.Errorf("%s: expected a #45bdf3 background got %+v", name, doc.Rects)
+		}
+		if len(doc.Paths) != 1 || doc.Paths[0].Fill != "#ffffff" {
+			t.Fatalf("%s: expected one white glyph path got %+v", name, doc.Paths)
+		}
+
+		// the glyph outlines cover the same pixels as the raster image
+		bg := color.Black
+		m, err := av.Draw(name, DrawOptions{Size: size, Background: bg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := inkBounds(m, bg)
+		got := inkBounds(rasterizePath(t, doc.Paths[0].D, size), color.Alpha{})
+		if d := got.Min.Sub(want.Min); d.X*d.X+d.Y*d.Y > 2 {
+			t.Errorf("%s: glyph paths at %v, raster glyphs at %v", name, got, want)
+		}
+		if d := got.Max.Sub(want.Max); d.X*d.X+d.Y*d.Y > 2 {
+			t.Errorf("%s: glyph paths at %v, raster glyphs at %v", name, got, want)
+		}
+	}
+}
+
+func TestInitialsAvatar_DrawBytesSVGShapes(t *testing.T) {
+	av := New("")
+
+	raw, _ := av.DrawBytes("John Doe", DrawOptions{Format: FormatSVG, Shape: ShapeCircle})
+	var doc svgDoc
+	if err := xml.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Circles) != 1 || doc.Circles[0].R != "24" || len(doc.Rects) != 0 {
+		t.Errorf("expected a circle of radius 24 got %s", raw)
+	}
+
+	raw, _ = av.DrawBytes("John Doe", DrawOptions{Format: FormatSVG, Shape: ShapeRoundedRect, Radius: 5})
+	doc = svgDoc{}
+	if err := xml.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Rects) != 1 || doc.Rects[0].RX != "5" {
+		t.Errorf("expected a rounded rect of radius 5 got %s", raw)
+	}
+
+	raw, _ = av.DrawBytes("John Doe", DrawOptions{Format: FormatSVG, Shape: ShapeSquircle})
+	doc = svgDoc{}
+	if err := xml.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Paths) != 2 || len(doc.Rects) != 0 {
+		t.Errorf("expected a squircle path and a glyph path got %s", raw)
+	}
+}
+
+func TestSVGFill(t *testing.T) {
+	stuffs := []struct {
+		c    color.Color
+		fill string
+	}{
+		{color.White, ` fill="#ffffff"`},
+		{color.RGBA{69, 189, 243, 255}, ` fill="#45bdf3"`},
+		{color.NRGBA{255, 0, 0, 128}, ` fill="#ff0000" fill-opacity="0.5"`},
+	}
+	for _, v := range stuffs {
+		if fill := svgFill(v.c); fill != v.fill {
+			t.Errorf("%v: expected %s got %s", v.c, v.fill, fill)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package avatar
+
+import (
+	"sync"
+	"time"
+)
+
+type ttlEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+type ttlCache struct {
+	counters
+	ttl time.Duration
+	now func() time.Time // for tests
+
+	mu    sync.Mutex
+	m     map[string]ttlEntry
+	size  int64
+	swept time.Time // last removal of expired values
+}
+
+// NewTTLCache returns an in-memory cache that drops values ttl after they
+// were set.
+func NewTTLCache(ttl time.Duration) Cache {
+	return &ttlCache{
+		ttl: ttl,
+		now: time.Now,
+		m:   make(map[string]ttlEntry),
+	}
+}
+
+func (c *ttlCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	e, ok := c.m[key]
+	if ok && !c.now().Before(e.expires) {
+		c.remove(key)
+		e, ok = ttlEntry{}, false
+	}
+	c.mu.Unlock()
+
+	c.count(ok)
+	return e.value, ok
+}
+
+func (c *ttlCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if now.Sub(c.swept) >= c.ttl {
+		// drop the expired values nobody asked for since
+		for k, e := range c.m {
+			if !now.Before(e.expires) {
+				c.remove(k)
+			}
+		}
+		c.swept = now
+	}
+	c.remove(key)
+	c.m[key] = ttlEntry{value, now.Add(c.ttl)}
+	c.size += int64(len(value))
+}
+
+func (c *ttlCache) Delete(key string) {
+	c.mu.Lock()
+	c.remove(key)
+	c.mu.Unlock()
+}
+
+// remove deletes key. c.mu must be held.
+func (c *ttlCache) remove(key string) {
+	if e, ok := c.m[key]; ok {
+		c.size -= int64(len(e.value))
+		delete(c.m, key)
+	}
+}
+
+func (c *ttlCache) Stats() CacheStats {
+	s := c.stats()
+	c.mu.Lock()
+	s.Items = len(c.m)
+	s.Bytes = c.size
+	c.mu.Unlock()
+	return s
+}